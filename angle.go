@@ -0,0 +1,31 @@
+package mpls
+
+// AngleCount returns the number of angles of the playlist, the most angles of any PlayItem
+func (mpls *MPLS) AngleCount() int {
+	angles := 1
+	for _, playitem := range mpls.Playlist.PlayItems {
+		if len(playitem.Angles)+1 > angles {
+			angles = len(playitem.Angles) + 1
+		}
+	}
+	return angles
+}
+
+// Angle returns the clip played for angle n, counting from 1.
+// PlayItems without angle n play angle 1
+func (pi PlayItem) Angle(n int) CLPI {
+	if n < 2 || n-2 >= len(pi.Angles) {
+		return pi.Clpi
+	}
+	return pi.Angles[n-2]
+}
+
+// SegmentMapForAngle returns the clips a player plays for angle n, counting from 1.
+// SegmentMapForAngle(1) is the same as SegmentMap
+func (mpls *MPLS) SegmentMapForAngle(n int) []string {
+	segments := make([]string, 0, len(mpls.Playlist.PlayItems))
+	for _, playitem := range mpls.Playlist.PlayItems {
+		segments = append(segments, playitem.Angle(n).ClipFile)
+	}
+	return segments
+}
@@ -0,0 +1,114 @@
+package mpls
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ChapterOptions controls the chapter exporters
+type ChapterOptions struct {
+	// Language is the ISO 639-2 code of the chapter names e.g. "eng", empty for none.
+	// OGM chapters have no language and ignore it
+	Language string
+
+	// Name returns the name of chapter n, counting from 1.
+	// If nil the chapters are named "Chapter 01", "Chapter 02" and so on
+	Name func(n int, chapter Chapter) string
+}
+
+func (opts ChapterOptions) name(n int, chapter Chapter) string {
+	if opts.Name != nil {
+		return opts.Name(n, chapter)
+	}
+	return fmt.Sprintf("Chapter %02d", n)
+}
+
+type matroskaChapters struct {
+	XMLName xml.Name `xml:"Chapters"`
+	Edition struct {
+		Atoms []matroskaAtom `xml:"ChapterAtom"`
+	} `xml:"EditionEntry"`
+}
+
+type matroskaAtom struct {
+	Start   string `xml:"ChapterTimeStart"`
+	End     string `xml:"ChapterTimeEnd"`
+	Display struct {
+		String   string `xml:"ChapterString"`
+		Language string `xml:"ChapterLanguage,omitempty"`
+	} `xml:"ChapterDisplay"`
+}
+
+// WriteMatroskaChapters writes the chapters as Matroska chapter XML for mkvmerge --chapters
+func (mpls *MPLS) WriteMatroskaChapters(w io.Writer, opts ChapterOptions) error {
+	var chapters matroskaChapters
+
+	for i, chapter := range mpls.Chapters {
+		var atom matroskaAtom
+		atom.Start = formatNanoseconds(chapter.Start.Duration())
+		atom.End = formatNanoseconds((chapter.Start + chapter.Duration).Duration())
+		atom.Display.String = opts.name(i+1, chapter)
+		atom.Display.Language = opts.Language
+		chapters.Edition.Atoms = append(chapters.Edition.Atoms, atom)
+	}
+
+	b, err := xml.MarshalIndent(chapters, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	_, _ = bw.WriteString(xml.Header)
+	_, _ = bw.WriteString("<!DOCTYPE Chapters SYSTEM \"matroskachapters.dtd\">\n")
+	_, _ = bw.Write(b)
+	_, _ = bw.WriteString("\n")
+	return bw.Flush()
+}
+
+// WriteOGMChapters writes the chapters as OGM CHAPTERxx= and CHAPTERxxNAME= lines
+func (mpls *MPLS) WriteOGMChapters(w io.Writer, opts ChapterOptions) error {
+	bw := bufio.NewWriter(w)
+	for i, chapter := range mpls.Chapters {
+		start := chapter.Start.Duration().Round(time.Millisecond)
+		fmt.Fprintf(bw, "CHAPTER%02d=%02d:%02d:%02d.%03d\n", i+1,
+			int(start.Hours()), int(start.Minutes())%60, int(start.Seconds())%60, start.Milliseconds()%1000)
+		fmt.Fprintf(bw, "CHAPTER%02dNAME=%s\n", i+1, opts.name(i+1, chapter))
+	}
+	return bw.Flush()
+}
+
+// WriteFFMetadata writes the chapters as an ffmpeg FFMETADATA1 file with a 1/45000 time base
+func (mpls *MPLS) WriteFFMetadata(w io.Writer, opts ChapterOptions) error {
+	bw := bufio.NewWriter(w)
+	_, _ = bw.WriteString(";FFMETADATA1\n")
+	for i, chapter := range mpls.Chapters {
+		_, _ = bw.WriteString("\n[CHAPTER]\nTIMEBASE=1/45000\n")
+		fmt.Fprintf(bw, "START=%d\nEND=%d\n", chapter.Start, chapter.Start+chapter.Duration)
+		fmt.Fprintf(bw, "title=%s\n", escapeFFMetadata(opts.name(i+1, chapter)))
+		if opts.Language != "" {
+			fmt.Fprintf(bw, "language=%s\n", escapeFFMetadata(opts.Language))
+		}
+	}
+	return bw.Flush()
+}
+
+// formatNanoseconds formats d as HH:MM:SS.nnnnnnnnn
+func formatNanoseconds(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%09d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Nanoseconds()%int64(time.Second))
+}
+
+// escapeFFMetadata escapes the characters special to FFMETADATA files
+func escapeFFMetadata(s string) string {
+	var escaped []rune
+	for _, r := range s {
+		switch r {
+		case '=', ';', '#', '\\', '\n':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, r)
+	}
+	return string(escaped)
+}
@@ -0,0 +1,152 @@
+package mpls
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exportPlaylist returns a playlist of one PlayItem of 1:01:40 with chapters at 0,
+// 1:01:01 and one tick and 1:01:30.001
+func exportPlaylist() *MPLS {
+	mpls := &MPLS{}
+	mpls.Playlist.PlayItems = []PlayItem{{InTime: 1000, OutTime: 1000 + 3700*TicksPerSecond, Clpi: CLPI{ClipFile: "00001", ClipID: "M2TS"}}}
+	for _, time := range []Ticks{0, 3661*TicksPerSecond + 1, 3690*TicksPerSecond + 45} {
+		mpls.MarkPlaylist.Marks = append(mpls.MarkPlaylist.Marks, Mark{Type: MarkEntry, Time: 1000 + time, PID: 0xFFFF})
+	}
+	mpls.update()
+	return mpls
+}
+
+func TestWriteMatroskaChapters(t *testing.T) {
+	var buf bytes.Buffer
+	opts := ChapterOptions{
+		Language: "ger",
+		Name: func(n int, chapter Chapter) string {
+			return fmt.Sprintf("Kapitel <%d> & PlayItem %d", n, chapter.PlayItemRef)
+		},
+	}
+	if err := exportPlaylist().WriteMatroskaChapters(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var atoms []string
+	for i, times := range [][2]string{
+		{"00:00:00.000000000", "01:01:01.000022222"},
+		{"01:01:01.000022222", "01:01:30.001000000"},
+		{"01:01:30.001000000", "01:01:40.000000000"},
+	} {
+		atoms = append(atoms, fmt.Sprintf(`    <ChapterAtom>
+      <ChapterTimeStart>%s</ChapterTimeStart>
+      <ChapterTimeEnd>%s</ChapterTimeEnd>
+      <ChapterDisplay>
+        <ChapterString>Kapitel &lt;%d&gt; &amp; PlayItem 0</ChapterString>
+        <ChapterLanguage>ger</ChapterLanguage>
+      </ChapterDisplay>
+    </ChapterAtom>`, times[0], times[1], i+1))
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE Chapters SYSTEM "matroskachapters.dtd">
+<Chapters>
+  <EditionEntry>
+` + strings.Join(atoms, "\n") + `
+  </EditionEntry>
+</Chapters>
+`
+	if buf.String() != want {
+		t.Errorf("matroska chapters =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// without a language the element is left out
+	buf.Reset()
+	if err := exportPlaylist().WriteMatroskaChapters(&buf, ChapterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "ChapterLanguage") || !strings.Contains(buf.String(), "<ChapterString>Chapter 03</ChapterString>") {
+		t.Errorf("matroska chapters with the default options =\n%s", buf.String())
+	}
+}
+
+func TestWriteOGMChapters(t *testing.T) {
+	var buf bytes.Buffer
+	// the language is ignored, OGM chapters have none
+	if err := exportPlaylist().WriteOGMChapters(&buf, ChapterOptions{Language: "eng"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `CHAPTER01=00:00:00.000
+CHAPTER01NAME=Chapter 01
+CHAPTER02=01:01:01.000
+CHAPTER02NAME=Chapter 02
+CHAPTER03=01:01:30.001
+CHAPTER03NAME=Chapter 03
+`
+	if buf.String() != want {
+		t.Errorf("OGM chapters =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteFFMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	opts := ChapterOptions{
+		Language: "eng",
+		Name: func(n int, chapter Chapter) string {
+			return []string{"a=b", "c;d#e", "f\\g\nh"}[n-1]
+		},
+	}
+	if err := exportPlaylist().WriteFFMetadata(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := `;FFMETADATA1
+
+[CHAPTER]
+TIMEBASE=1/45000
+START=0
+END=164745001
+title=a\=b
+language=eng
+
+[CHAPTER]
+TIMEBASE=1/45000
+START=164745001
+END=166050045
+title=c\;d\#e
+language=eng
+
+[CHAPTER]
+TIMEBASE=1/45000
+START=166050045
+END=166500000
+title=f\\g\
+h
+language=eng
+`
+	if buf.String() != want {
+		t.Errorf("FFMETADATA =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestChaptersMarkPastPlayItem(t *testing.T) {
+	mpls := editPlaylist()
+	// the mark is 4000 past the end of PlayItem 0, it is placed at the end of the PlayItem
+	mpls.MarkPlaylist.Marks = append(mpls.MarkPlaylist.Marks, Mark{Type: MarkEntry, PlayItemRef: 0, Time: 9000, PID: 0xFFFF})
+	mpls.update()
+
+	var got [][3]Ticks
+	for _, chapter := range mpls.Chapters {
+		got = append(got, [3]Ticks{chapter.Start, chapter.Duration, Ticks(chapter.PlayItemRef)})
+	}
+	want := [][3]Ticks{
+		{0, 2000, 0},
+		{2000, 2000, 0},
+		{4000, 0, 1},
+		{4000, 2000, 0},
+		{6000, 2000, 1},
+		{8000, 2000, 2},
+		{10000, 2000, 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chapters (start, duration, play item) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+// Package clpi parses the clip information files found in BDMV/CLIPINF
+package clpi
+
+// Application types
+const (
+	ATMainTSMovie           = 1
+	ATMainTSTimedSlideshow  = 2
+	ATMainTSBrowsableSlides = 3
+	ATSubTSBrowsableSlides  = 4
+	ATSubTSInteractiveMenu  = 5
+	ATSubTSTextSubtitle     = 6
+	ATSubTSElementary       = 7
+	ATSubTSDependentView    = 8
+)
+
+// CPI types
+const (
+	CPIEPMap = 1
+)
+
+// Coding types
+const (
+	CTMPEG1Video   = 0x01
+	CTMPEG2Video   = 0x02
+	CTMPEG1Audio   = 0x03
+	CTMPEG2Audio   = 0x04
+	CTMVC          = 0x20
+	CTHEVC         = 0x24
+	CTLPCM         = 0x80
+	CTAC3          = 0x81
+	CTDTS          = 0x82
+	CTTRUEHD       = 0x83
+	CTAC3Plus      = 0x84
+	CTDTSHD        = 0x85
+	CTDTSHDMaster  = 0x86
+	CTPG           = 0x90
+	CTIG           = 0x91
+	CTTextSubtitle = 0x92
+	CTAC3PlusSec   = 0xa1
+	CTDTSHDSec     = 0xa2
+	CTVC1          = 0xea
+	CTH264         = 0x1b
+)
+
+// SourcePacketSize is the size in bytes of a source packet in an m2ts file
+const SourcePacketSize = 192
+
+// CLPI is a struct representing a CLPI file
+type CLPI struct {
+	FileType           string
+	Version            string
+	SequenceInfoStart  int
+	ProgramInfoStart   int
+	CPIStart           int
+	ClipMarkStart      int
+	ExtensionDataStart int
+	ClipInfo           ClipInfo
+	SequenceInfo       SequenceInfo
+	ProgramInfo        ProgramInfo
+	CPI                CPI
+	ClipMark           ClipMark
+	ExtensionData      ExtensionData
+	Warnings           []Warning
+}
+
+// ClipInfo holds the type and recording rate of the clip
+type ClipInfo struct {
+	Len               int
+	ClipStreamType    byte
+	ApplicationType   byte
+	IsATCDelta        bool
+	TSRecordingRate   uint32 // bytes per second
+	SourcePacketCount uint32
+	TSTypeInfo        TSTypeInfo
+	ATCDeltas         []ATCDelta
+	FontIDs           []string
+}
+
+// TSTypeInfo identifies the format of the transport stream
+type TSTypeInfo struct {
+	Len      uint16
+	Validity byte
+	FormatID string
+	Data     []byte
+}
+
+// ATCDelta links the clip to the following clip file
+type ATCDelta struct {
+	Delta    uint32
+	FileID   string
+	FileCode string
+}
+
+// SequenceInfo holds the ATC and STC sequences of the clip
+type SequenceInfo struct {
+	Len          int
+	ATCSequences []ATCSequence
+}
+
+// ATCSequence is a run of source packets with a continuous arrival time clock
+type ATCSequence struct {
+	SPNATCStart  uint32
+	OffsetSTCID  byte
+	STCSequences []STCSequence
+}
+
+// STCSequence is a run of source packets with a continuous system time clock.
+// Times are 45 kHz timestamps
+type STCSequence struct {
+	PCRPID                uint16
+	SPNSTCStart           uint32
+	PresentationStartTime uint32
+	PresentationEndTime   uint32
+}
+
+// ProgramInfo holds the programs of the clip
+type ProgramInfo struct {
+	Len      int
+	Programs []Program
+}
+
+// Program lists the elementary streams of a program sequence
+type Program struct {
+	SPNProgramSequenceStart uint32
+	ProgramMapPID           uint16
+	GroupCount              byte
+	Streams                 []Stream
+}
+
+// Stream is an elementary stream of a program
+type Stream struct {
+	PID        uint16
+	CodingInfo StreamCodingInfo
+}
+
+// StreamCodingInfo holds the coding parameters of an elementary stream
+type StreamCodingInfo struct {
+	Len              byte
+	CodingType       byte
+	Format           byte
+	Rate             byte
+	AspectRatio      byte
+	OCFlag           bool
+	CRFlag           bool
+	DynamicRangeType byte
+	ColorSpace       byte
+	HDRPlus          bool
+	CharacterCode    byte
+	Language         string
+}
+
+// CPI holds the characteristic point information of the clip
+type CPI struct {
+	Len   int
+	Type  byte
+	EPMap EPMap
+}
+
+// EPMap holds the entry points of every stream in the clip
+type EPMap struct {
+	Streams []EPMapStream
+}
+
+// EPMapStream holds the entry points of one stream
+type EPMapStream struct {
+	PID        uint16
+	StreamType byte
+	Coarse     []EPCoarse
+	Fine       []EPFine
+}
+
+// EPCoarse is a coarse entry point, it holds the high bits of the PTS and SPN
+type EPCoarse struct {
+	RefEPFineID uint32
+	PTSEP       uint16
+	SPNEP       uint32
+}
+
+// EPFine is a fine entry point, it holds the low bits of the PTS and SPN
+type EPFine struct {
+	IsAngleChangePoint bool
+	IEndPositionOffset byte
+	PTSEP              uint16
+	SPNEP              uint32
+}
+
+// EntryPoint is a complete entry point.
+// PTS is a 90 kHz timestamp and SPN is the source packet number in the m2ts file
+type EntryPoint struct {
+	PTS uint64
+	SPN uint32
+}
+
+// ClipMark holds the ClipMark section. It is deliberately left raw: players do not use it,
+// authoring tools write it empty and its layout is not publicly documented, so there is nothing to decode it against.
+// Data holds the Len bytes following the length field as they are in the file
+type ClipMark struct {
+	Len  int
+	Data []byte
+}
+
+// ExtensionData holds the entries of the ExtensionData block
+type ExtensionData struct {
+	Len            int
+	DataBlockStart int
+	Entries        []ExtensionEntry
+}
+
+// ExtensionEntry is a single entry of the ExtensionData block.
+// Start is relative to the start of the ExtensionData block
+type ExtensionEntry struct {
+	ID1   uint16
+	ID2   uint16
+	Start int
+	Len   int
+	Data  []byte
+}
+
+// Bitrate returns the recording rate of the transport stream in bits per second
+func (ci ClipInfo) Bitrate() int64 {
+	return int64(ci.TSRecordingRate) * 8
+}
+
+// EntryPoints combines the coarse and fine entries into complete entry points
+func (eps EPMapStream) EntryPoints() []EntryPoint {
+	var (
+		points = make([]EntryPoint, 0, len(eps.Fine))
+		coarse int
+	)
+
+	if len(eps.Coarse) == 0 {
+		return points
+	}
+
+	for i, fine := range eps.Fine {
+		for coarse+1 < len(eps.Coarse) && int(eps.Coarse[coarse+1].RefEPFineID) <= i {
+			coarse++
+		}
+		points = append(points, EntryPoint{
+			PTS: uint64(eps.Coarse[coarse].PTSEP&^0x01)<<19 | uint64(fine.PTSEP)<<9,
+			SPN: eps.Coarse[coarse].SPNEP&^0x1FFFF | fine.SPNEP,
+		})
+	}
+	return points
+}
+
+// Lookup returns the last entry point at or before the 90 kHz timestamp pts
+func (eps EPMapStream) Lookup(pts uint64) (EntryPoint, bool) {
+	var (
+		found EntryPoint
+		ok    bool
+	)
+
+	for _, point := range eps.EntryPoints() {
+		if point.PTS > pts {
+			break
+		}
+		found, ok = point, true
+	}
+	return found, ok
+}
+
+// Offset returns the byte offset of the entry point in the m2ts file
+func (ep EntryPoint) Offset() int64 {
+	return int64(ep.SPN) * SourcePacketSize
+}
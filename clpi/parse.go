@@ -0,0 +1,496 @@
+package clpi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"timmy.narnian.us/mpls/internal/bdparse"
+)
+
+// errReader is the shared reader with the helpers specific to clip information files
+type errReader struct {
+	bdparse.Reader
+}
+
+// section parses the section at start with parse unless start is 0
+func (er *errReader) section(name string, start int, parse func(*errReader) error) error {
+	if start == 0 || er.Err != nil {
+		return er.Err
+	}
+
+	er.Enter(name)
+	defer er.Leave()
+	_, _ = er.Seek(int64(start), io.SeekStart)
+	return parse(er)
+}
+
+// Parse parses a CLPI file into a CLPI struct
+func Parse(reader io.Reader) (clpi CLPI, err error) {
+	var (
+		file []byte
+	)
+
+	file, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return CLPI{}, err
+	}
+
+	err = clpi.Parse(file)
+	return clpi, err
+}
+
+// Parse reads CLPI data from a byte slice
+func (clpi *CLPI) Parse(file []byte) error {
+	var (
+		buf [10]byte
+		err error
+	)
+
+	reader := &errReader{
+		Reader: bdparse.Reader{
+			RS:     bytes.NewReader(file),
+			Format: "clpi",
+		},
+	}
+	defer func() {
+		clpi.Warnings = reader.Warnings
+	}()
+
+	_, err = reader.Read(buf[:8])
+	if err != nil {
+		return err
+	}
+	str := string(buf[:8])
+	if str[:4] != "HDMV" {
+		return reader.Fail(0, fmt.Errorf("%w: not a clpi file it must start with 'HDMV' it started with '%s'", ErrBadMagic, str[:4]))
+	}
+	clpi.FileType = str[:4]
+	clpi.Version = str[4:8]
+
+	switch clpi.Version {
+	case "0100", "0200", "0300":
+	default:
+		reader.Warn(WarnVersion, 4, "clpi may not work it is version %s", clpi.Version)
+	}
+
+	clpi.SequenceInfoStart, _ = bdparse.ReadInt32(reader, buf[:])
+	clpi.ProgramInfoStart, _ = bdparse.ReadInt32(reader, buf[:])
+	clpi.CPIStart, _ = bdparse.ReadInt32(reader, buf[:])
+	clpi.ClipMarkStart, _ = bdparse.ReadInt32(reader, buf[:])
+	clpi.ExtensionDataStart, _ = bdparse.ReadInt32(reader, buf[:])
+
+	_, _ = reader.Seek(12, io.SeekCurrent)
+
+	reader.Enter("ClipInfo")
+	err = clpi.ClipInfo.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+
+	_ = reader.section("SequenceInfo", clpi.SequenceInfoStart, clpi.SequenceInfo.parse)
+	_ = reader.section("ProgramInfo", clpi.ProgramInfoStart, clpi.ProgramInfo.parse)
+	_ = reader.section("CPI", clpi.CPIStart, clpi.CPI.parse)
+	_ = reader.section("ClipMark", clpi.ClipMarkStart, clpi.ClipMark.parse)
+	_ = reader.section("ExtensionData", clpi.ExtensionDataStart, clpi.ExtensionData.parse)
+
+	return reader.Err
+}
+
+// parse reads ClipInfo data from an *errReader
+func (ci *ClipInfo) parse(reader *errReader) error {
+	var (
+		buf   [10]byte
+		start int64
+		pos   int64
+	)
+
+	ci.Len, _ = bdparse.ReadInt32(reader, buf[:])
+
+	start = reader.Pos()
+
+	_, _ = reader.Read(buf[:8])
+
+	ci.ClipStreamType = buf[2]
+	ci.ApplicationType = buf[3]
+	ci.IsATCDelta = buf[7]&0x01 != 0
+
+	ci.TSRecordingRate, _ = bdparse.ReadUInt32(reader, buf[:])
+	ci.SourcePacketCount, _ = bdparse.ReadUInt32(reader, buf[:])
+
+	_, _ = reader.Seek(128, io.SeekCurrent)
+
+	ci.TSTypeInfo.Len, _ = bdparse.ReadUInt16(reader, buf[:])
+	pos = reader.Pos()
+	if ci.TSTypeInfo.Len >= 5 {
+		_, _ = reader.Read(buf[:5])
+		ci.TSTypeInfo.Validity = buf[0]
+		ci.TSTypeInfo.FormatID = string(buf[1:5])
+		ci.TSTypeInfo.Data = reader.Bytes(int64(ci.TSTypeInfo.Len) - 5)
+	}
+	_, _ = reader.Seek(pos+int64(ci.TSTypeInfo.Len), io.SeekStart)
+
+	if ci.IsATCDelta {
+		_, _ = reader.Read(buf[:2])
+		count := int(buf[1])
+		for i := 0; i < count && reader.Err == nil; i++ {
+			var delta ATCDelta
+			delta.Delta, _ = bdparse.ReadUInt32(reader, buf[:])
+			_, _ = reader.Read(buf[:10])
+			delta.FileID = string(buf[:5])
+			delta.FileCode = string(buf[5:9])
+			ci.ATCDeltas = append(ci.ATCDeltas, delta)
+		}
+	}
+
+	if ci.ApplicationType == ATSubTSTextSubtitle {
+		_, _ = reader.Read(buf[:2])
+		count := int(buf[1])
+		for i := 0; i < count && reader.Err == nil; i++ {
+			_, _ = reader.Read(buf[:6])
+			ci.FontIDs = append(ci.FontIDs, string(buf[:5]))
+		}
+	}
+
+	return reader.Align(start, int64(ci.Len), nil)
+}
+
+// parse reads SequenceInfo data from an *errReader
+func (si *SequenceInfo) parse(reader *errReader) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+	)
+
+	si.Len, _ = bdparse.ReadInt32(reader, buf[:])
+
+	start = reader.Pos()
+
+	_, _ = reader.Read(buf[:2])
+	count := int(buf[1])
+
+	err = reader.Count(count, 6, start+int64(si.Len))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		var atc ATCSequence
+		atc.SPNATCStart, _ = bdparse.ReadUInt32(reader, buf[:])
+		_, _ = reader.Read(buf[:2])
+		stcCount := int(buf[0])
+		atc.OffsetSTCID = buf[1]
+
+		reader.Enter("ATCSequences[%d]", i)
+		err = reader.Count(stcCount, 14, start+int64(si.Len))
+		for j := 0; j < stcCount && err == nil; j++ {
+			var stc STCSequence
+			stc.PCRPID, _ = bdparse.ReadUInt16(reader, buf[:])
+			stc.SPNSTCStart, _ = bdparse.ReadUInt32(reader, buf[:])
+			stc.PresentationStartTime, _ = bdparse.ReadUInt32(reader, buf[:])
+			stc.PresentationEndTime, err = bdparse.ReadUInt32(reader, buf[:])
+			atc.STCSequences = append(atc.STCSequences, stc)
+		}
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		si.ATCSequences = append(si.ATCSequences, atc)
+	}
+
+	return reader.Align(start, int64(si.Len), nil)
+}
+
+// parse reads ProgramInfo data from an *errReader
+func (pi *ProgramInfo) parse(reader *errReader) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+	)
+
+	pi.Len, _ = bdparse.ReadInt32(reader, buf[:])
+
+	start = reader.Pos()
+
+	_, _ = reader.Read(buf[:2])
+	count := int(buf[1])
+
+	err = reader.Count(count, 8, start+int64(pi.Len))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		var program Program
+		reader.Enter("Programs[%d]", i)
+		err = program.parse(reader, start+int64(pi.Len))
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		pi.Programs = append(pi.Programs, program)
+	}
+
+	return reader.Align(start, int64(pi.Len), nil)
+}
+
+// parse reads Program data from an *errReader, end is the end of the ProgramInfo section
+func (p *Program) parse(reader *errReader, end int64) error {
+	var (
+		buf [10]byte
+		err error
+	)
+
+	p.SPNProgramSequenceStart, _ = bdparse.ReadUInt32(reader, buf[:])
+	p.ProgramMapPID, _ = bdparse.ReadUInt16(reader, buf[:])
+	_, _ = reader.Read(buf[:2])
+	count := int(buf[0])
+	p.GroupCount = buf[1]
+
+	err = reader.Count(count, 3, end)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		var stream Stream
+		stream.PID, _ = bdparse.ReadUInt16(reader, buf[:])
+		reader.Enter("Streams[%d]", i)
+		err = stream.CodingInfo.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		p.Streams = append(p.Streams, stream)
+	}
+
+	return reader.Err
+}
+
+// parse reads StreamCodingInfo data from an *errReader
+func (sci *StreamCodingInfo) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+	)
+
+	_, _ = reader.Read(buf[:1])
+	sci.Len = buf[0]
+
+	raw := reader.Bytes(int64(sci.Len))
+	copy(buf[:], raw)
+
+	sci.CodingType = buf[0]
+	switch sci.CodingType {
+	case CTMPEG1Video, CTMPEG2Video, CTVC1, CTH264, CTMVC, CTHEVC:
+		sci.Format = buf[1] >> 4
+		sci.Rate = buf[1] & 0x0F
+		sci.AspectRatio = buf[2] >> 4
+		sci.OCFlag = buf[2]&0x02 != 0
+		if sci.CodingType == CTHEVC {
+			sci.CRFlag = buf[2]&0x01 != 0
+			sci.DynamicRangeType = buf[3] >> 4
+			sci.ColorSpace = buf[3] & 0x0F
+			sci.HDRPlus = buf[4]&0x80 != 0
+		}
+
+	case CTMPEG1Audio, CTMPEG2Audio, CTLPCM, CTAC3, CTDTS, CTTRUEHD, CTAC3Plus, CTDTSHD, CTDTSHDMaster, CTAC3PlusSec, CTDTSHDSec:
+		sci.Format = buf[1] >> 4
+		sci.Rate = buf[1] & 0x0F
+		sci.Language = string(buf[2:5])
+
+	case CTPG, CTIG:
+		sci.Language = string(buf[1:4])
+
+	case CTTextSubtitle:
+		sci.CharacterCode = buf[1]
+		sci.Language = string(buf[2:5])
+	}
+
+	return reader.Err
+}
+
+// parse reads CPI data from an *errReader
+func (cpi *CPI) parse(reader *errReader) error {
+	var (
+		buf   [10]byte
+		start int64
+	)
+
+	cpi.Len, _ = bdparse.ReadInt32(reader, buf[:])
+	if cpi.Len == 0 {
+		return reader.Err
+	}
+
+	start = reader.Pos()
+
+	_, _ = reader.Read(buf[:2])
+	cpi.Type = buf[1] & 0x0F
+
+	if cpi.Type == CPIEPMap {
+		reader.Enter("EPMap")
+		err := cpi.EPMap.parse(reader, start+int64(cpi.Len))
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _ = reader.Seek(start+int64(cpi.Len), io.SeekStart)
+	return reader.Err
+}
+
+// parse reads EPMap data from an *errReader, end is the end of the CPI section
+func (epm *EPMap) parse(reader *errReader, end int64) error {
+	var (
+		buf    [10]byte
+		err    error
+		start  int64
+		starts []uint32
+		coarse []int
+		fine   []int
+	)
+
+	start = reader.Pos()
+
+	_, _ = reader.Read(buf[:2])
+	count := int(buf[1])
+
+	err = reader.Count(count, 12, end)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		var stream EPMapStream
+		stream.PID, _ = bdparse.ReadUInt16(reader, buf[:])
+		_, _ = reader.Read(buf[2:8])
+		buf[0], buf[1] = 0, 0
+		v := binary.BigEndian.Uint64(buf[:8])
+		stream.StreamType = byte(v >> 34 & 0x0F)
+		coarse = append(coarse, int(v>>18&0xFFFF))
+		fine = append(fine, int(v&0x3FFFF))
+		address, _ := bdparse.ReadUInt32(reader, buf[:])
+		starts = append(starts, address)
+		epm.Streams = append(epm.Streams, stream)
+	}
+
+	for i := range epm.Streams {
+		reader.Enter("Streams[%d]", i)
+		err = epm.Streams[i].parse(reader, start+int64(starts[i]), coarse[i], fine[i], end)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	return reader.Err
+}
+
+// parse reads the coarse and fine entries of an EPMapStream located at start from an *errReader
+func (eps *EPMapStream) parse(reader *errReader, start int64, coarse, fine int, end int64) error {
+	var (
+		buf [10]byte
+		err error
+		v   uint32
+	)
+
+	_, _ = reader.Seek(start, io.SeekStart)
+	fineStart, _ := bdparse.ReadUInt32(reader, buf[:])
+
+	err = reader.Count(coarse, 8, end)
+	if err != nil {
+		return err
+	}
+
+	eps.Coarse = make([]EPCoarse, 0, coarse)
+	for i := 0; i < coarse; i++ {
+		var entry EPCoarse
+		v, _ = bdparse.ReadUInt32(reader, buf[:])
+		entry.RefEPFineID = v >> 14
+		entry.PTSEP = uint16(v & 0x3FFF)
+		entry.SPNEP, _ = bdparse.ReadUInt32(reader, buf[:])
+		eps.Coarse = append(eps.Coarse, entry)
+	}
+
+	_, _ = reader.Seek(start+int64(fineStart), io.SeekStart)
+
+	err = reader.Count(fine, 4, end)
+	if err != nil {
+		return err
+	}
+
+	eps.Fine = make([]EPFine, 0, fine)
+	for i := 0; i < fine; i++ {
+		var entry EPFine
+		v, _ = bdparse.ReadUInt32(reader, buf[:])
+		entry.IsAngleChangePoint = v>>31 != 0
+		entry.IEndPositionOffset = byte(v >> 28 & 0x07)
+		entry.PTSEP = uint16(v >> 17 & 0x07FF)
+		entry.SPNEP = v & 0x1FFFF
+		eps.Fine = append(eps.Fine, entry)
+	}
+
+	return reader.Err
+}
+
+// parse reads ClipMark data from an *errReader
+func (cm *ClipMark) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+	)
+
+	cm.Len, _ = bdparse.ReadInt32(reader, buf[:])
+	cm.Data = reader.Bytes(int64(cm.Len))
+
+	return reader.Err
+}
+
+// parse reads ExtensionData data from an *errReader
+func (ed *ExtensionData) parse(reader *errReader) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+	)
+
+	start = reader.Pos()
+
+	ed.Len, _ = bdparse.ReadInt32(reader, buf[:])
+	if ed.Len == 0 {
+		return reader.Err
+	}
+
+	ed.DataBlockStart, _ = bdparse.ReadInt32(reader, buf[:])
+
+	_, _ = reader.Read(buf[:4])
+	count := int(buf[3])
+
+	err = reader.Count(count, 12, start+4+int64(ed.Len))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		var entry ExtensionEntry
+		entry.ID1, _ = bdparse.ReadUInt16(reader, buf[:])
+		entry.ID2, _ = bdparse.ReadUInt16(reader, buf[:])
+		entry.Start, _ = bdparse.ReadInt32(reader, buf[:])
+		entry.Len, _ = bdparse.ReadInt32(reader, buf[:])
+		ed.Entries = append(ed.Entries, entry)
+	}
+
+	for i := range ed.Entries {
+		entry := &ed.Entries[i]
+		reader.Enter("Entries[%d]", i)
+		_, _ = reader.Seek(start+int64(entry.Start), io.SeekStart)
+		entry.Data = reader.Bytes(int64(entry.Len))
+		reader.Leave()
+	}
+
+	return reader.Err
+}
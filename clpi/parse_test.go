@@ -0,0 +1,174 @@
+package clpi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "timmy.narnian.us/mpls/internal/bdtest"
+)
+
+// testFile lays out a clip information file with a movie clip, one ATC and STC sequence,
+// an H.264, HEVC, AC-3 and PG stream, an EP map for the video stream and a ClipMark section
+func testFile(version string) []byte {
+	clipInfo := Len32(Cat(
+		U16(0), U8(1), U8(ATMainTSMovie), Fill(3, 0), U8(0),
+		U32(6000000), U32(1234),
+		Fill(128, 0),
+		Len16(Cat(U8(0x80), []byte("HDMV"), []byte{1, 2, 3})),
+	))
+	sequenceInfo := Len32(Cat(
+		U8(0), U8(1),
+		U32(0), U8(1), U8(0),
+		U16(0x1001), U32(0), U32(27000000), U32(31500000),
+	))
+	programInfo := Len32(Cat(
+		U8(0), U8(1),
+		U32(0), U16(0x0100), U8(4), U8(0),
+		U16(0x1011), Len8(Cat(U8(CTH264), U8(0x61), U8(0x32), U8(0), U8(0))),
+		U16(0x1015), Len8(Cat(U8(CTHEVC), U8(0x81), U8(0x33), U8(0x12), U8(0x80))),
+		U16(0x1100), Len8(Cat(U8(CTAC3), U8(0x31), []byte("eng"))),
+		U16(0x1200), Len8(Cat(U8(CTPG), []byte("jpn"), U8(0))),
+	))
+
+	// one stream with 2 coarse and 3 fine entries, the stream data follows the 14 bytes of the table
+	epMapStream := Cat(
+		U32(4+2*8),
+		U32(0<<14|0x0002), U32(0x20000),
+		U32(2<<14|0x0004), U32(0x40000),
+		U32(1<<31|1<<28|5<<17|0x10), U32(6<<17|0x20), U32(7<<17|0x30),
+	)
+	cpi := Len32(Cat(
+		U16(CPIEPMap),
+		U8(0), U8(1),
+		U16(0x1011), U64(1<<34 | 2<<18 | 3)[2:], U32(14),
+		epMapStream,
+	))
+	clipMark := Len32([]byte{0xC1, 0xC2})
+
+	sequenceInfoStart := 40 + len(clipInfo)
+	programInfoStart := sequenceInfoStart + len(sequenceInfo)
+	cpiStart := programInfoStart + len(programInfo)
+	clipMarkStart := cpiStart + len(cpi)
+
+	return Cat(
+		[]byte("HDMV"+version),
+		U32(uint32(sequenceInfoStart)), U32(uint32(programInfoStart)), U32(uint32(cpiStart)), U32(uint32(clipMarkStart)), U32(0),
+		Fill(12, 0),
+		clipInfo, sequenceInfo, programInfo, cpi, clipMark,
+	)
+}
+
+func TestParse(t *testing.T) {
+	var clpi CLPI
+	if err := clpi.Parse(testFile("0200")); err != nil {
+		t.Fatal(err)
+	}
+	if len(clpi.Warnings) != 0 {
+		t.Errorf("warnings: %v", clpi.Warnings)
+	}
+
+	ci := clpi.ClipInfo
+	if ci.ApplicationType != ATMainTSMovie || ci.TSRecordingRate != 6000000 || ci.Bitrate() != 48000000 || ci.SourcePacketCount != 1234 {
+		t.Errorf("ClipInfo = %+v", ci)
+	}
+	if want := (TSTypeInfo{Len: 8, Validity: 0x80, FormatID: "HDMV", Data: []byte{1, 2, 3}}); !reflect.DeepEqual(ci.TSTypeInfo, want) {
+		t.Errorf("TSTypeInfo = %+v, want %+v", ci.TSTypeInfo, want)
+	}
+
+	want := []ATCSequence{{STCSequences: []STCSequence{{PCRPID: 0x1001, PresentationStartTime: 27000000, PresentationEndTime: 31500000}}}}
+	if !reflect.DeepEqual(clpi.SequenceInfo.ATCSequences, want) {
+		t.Errorf("ATCSequences = %+v, want %+v", clpi.SequenceInfo.ATCSequences, want)
+	}
+
+	if len(clpi.ProgramInfo.Programs) != 1 || len(clpi.ProgramInfo.Programs[0].Streams) != 4 {
+		t.Fatalf("Programs = %+v", clpi.ProgramInfo.Programs)
+	}
+	program := clpi.ProgramInfo.Programs[0]
+	if program.ProgramMapPID != 0x0100 {
+		t.Errorf("ProgramMapPID = %#x", program.ProgramMapPID)
+	}
+	streams := []StreamCodingInfo{
+		{Len: 5, CodingType: CTH264, Format: 6, Rate: 1, AspectRatio: 3, OCFlag: true},
+		{Len: 5, CodingType: CTHEVC, Format: 8, Rate: 1, AspectRatio: 3, OCFlag: true, CRFlag: true, DynamicRangeType: 1, ColorSpace: 2, HDRPlus: true},
+		{Len: 5, CodingType: CTAC3, Format: 3, Rate: 1, Language: "eng"},
+		{Len: 5, CodingType: CTPG, Language: "jpn"},
+	}
+	for i, stream := range program.Streams {
+		if !reflect.DeepEqual(stream.CodingInfo, streams[i]) {
+			t.Errorf("Streams[%d].CodingInfo = %+v, want %+v", i, stream.CodingInfo, streams[i])
+		}
+	}
+
+	if clpi.CPI.Type != CPIEPMap || len(clpi.CPI.EPMap.Streams) != 1 {
+		t.Fatalf("CPI = %+v", clpi.CPI)
+	}
+	eps := clpi.CPI.EPMap.Streams[0]
+	if eps.PID != 0x1011 || eps.StreamType != 1 || len(eps.Coarse) != 2 || len(eps.Fine) != 3 {
+		t.Fatalf("EPMap stream = %+v", eps)
+	}
+	if want := (EPFine{IsAngleChangePoint: true, IEndPositionOffset: 1, PTSEP: 5, SPNEP: 0x10}); eps.Fine[0] != want {
+		t.Errorf("Fine[0] = %+v, want %+v", eps.Fine[0], want)
+	}
+	points := []EntryPoint{
+		{PTS: 2<<19 | 5<<9, SPN: 0x20010},
+		{PTS: 2<<19 | 6<<9, SPN: 0x20020},
+		{PTS: 4<<19 | 7<<9, SPN: 0x40030},
+	}
+	if !reflect.DeepEqual(eps.EntryPoints(), points) {
+		t.Errorf("EntryPoints = %+v, want %+v", eps.EntryPoints(), points)
+	}
+	if point, ok := eps.Lookup(points[2].PTS - 1); !ok || point != points[1] {
+		t.Errorf("Lookup = %+v %v, want %+v", point, ok, points[1])
+	}
+	if point := points[2]; point.Offset() != 0x40030*SourcePacketSize {
+		t.Errorf("Offset = %d", point.Offset())
+	}
+
+	if !reflect.DeepEqual(clpi.ClipMark, ClipMark{Len: 2, Data: []byte{0xC1, 0xC2}}) {
+		t.Errorf("ClipMark = %+v", clpi.ClipMark)
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	file := testFile("0200")
+	for n := 0; n < len(file); n++ {
+		var clpi CLPI
+		err := clpi.Parse(file[:n])
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || !(errors.Is(err, ErrTruncated) || errors.Is(err, ErrCountOverflow)) {
+			t.Fatalf("parsing %d of %d bytes returned %v, want a truncation error", n, len(file), err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	file := testFile("0200")
+	copy(file, "MPLS")
+	var clpi CLPI
+	if err := clpi.Parse(file); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("wrong magic returned %v, want ErrBadMagic", err)
+	}
+
+	// 255 ATC sequences do not fit in the SequenceInfo section
+	file = testFile("0200")
+	start := int(file[8])<<24 | int(file[9])<<16 | int(file[10])<<8 | int(file[11])
+	file[start+5] = 255
+	clpi = CLPI{}
+	err := clpi.Parse(file)
+	var parseErr *ParseError
+	if !errors.Is(err, ErrCountOverflow) || !errors.As(err, &parseErr) || parseErr.Section != "SequenceInfo" {
+		t.Errorf("too many ATC sequences returned %v, want ErrCountOverflow in SequenceInfo", err)
+	}
+}
+
+func TestParseVersionWarning(t *testing.T) {
+	var clpi CLPI
+	if err := clpi.Parse(testFile("0900")); err != nil {
+		t.Fatal(err)
+	}
+	want := []Warning{{Kind: WarnVersion, Expected: 4, Actual: 8, Message: "clpi may not work it is version 0900"}}
+	if !reflect.DeepEqual(clpi.Warnings, want) {
+		t.Errorf("Warnings = %+v, want %+v", clpi.Warnings, want)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,44 +12,89 @@ import (
 
 func main() {
 	var (
-		err     error
-		dir     *os.File
-		files   []string
-		Seconds int64
+		err        error
+		disc       *mpls.Disc
+		names      []string
+		Seconds    int64
+		Rank       bool
+		Collapse   bool
+		Maze       bool
+		duplicates = map[string][]string{}
 	)
 	flag.Int64Var(&Seconds, "s", 120, "Minimum duration of playlist")
 	flag.Int64Var(&Seconds, "seconds", 120, "Minimum duration of playlist")
+	flag.BoolVar(&Rank, "rank", false, "Rank playlists as main feature candidates")
+	flag.BoolVar(&Collapse, "d", false, "Collapse duplicate playlists")
+	flag.BoolVar(&Collapse, "duplicates", false, "Collapse duplicate playlists")
+	flag.BoolVar(&Maze, "m", false, "Look for a playlist maze")
+	flag.BoolVar(&Maze, "maze", false, "Look for a playlist maze")
 	flag.Parse()
-	name := filepath.Join(flag.Arg(0), "BDMV", "PLAYLIST")
-	dir, err = os.Open(name)
+	disc, err = mpls.OpenDisc(flag.Arg(0))
 	if err != nil {
 		panic(err)
 	}
-	files, err = dir.Readdirnames(0)
+	if Rank {
+		var candidates []mpls.Candidate
+		candidates, err = disc.MainFeature()
+		if err != nil {
+			panic(err)
+		}
+		for _, c := range candidates {
+			fmt.Printf("%s.mpls %6.1f\n", c.Name, c.Score)
+			for _, reason := range c.Reasons {
+				fmt.Printf("\t%s\n", reason)
+			}
+		}
+		return
+	}
+	names, err = disc.PlaylistNames()
 	if err != nil {
 		panic(err)
 	}
-	for _, v := range files {
+	if Maze {
+		var maze mpls.Maze
+		maze, err = disc.DetectMaze()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else if maze.Detected {
+			fmt.Fprintf(os.Stderr, "playlist maze: probable real playlist is %s.mpls\n", maze.Probable)
+			for _, reason := range maze.Reasons {
+				fmt.Fprintf(os.Stderr, "\t%s\n", reason)
+			}
+		}
+	}
+	if Collapse {
+		var groups []mpls.DuplicateGroup
+		groups, err = disc.GroupDuplicates(mpls.TicksPerSecond / 2)
+		if err != nil {
+			panic(err)
+		}
+		names = names[:0]
+		for _, group := range groups {
+			names = append(names, group.Canonical)
+			duplicates[group.Canonical] = group.Duplicates()
+		}
+	}
+	for _, v := range names {
 		var (
-			file     *os.File
-			playlist mpls.MPLS
+			playlist *mpls.MPLS
 			duration time.Duration
 		)
 
-		file, err = os.Open(filepath.Join(name, v))
+		playlist, err = disc.Playlist(v)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			continue
 		}
-
-		playlist, err = mpls.Parse(file)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			continue
+		for _, warning := range playlist.Warnings {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", v, warning)
 		}
-		if playlist.Duration > Seconds {
-			duration = time.Duration(playlist.Duration) * time.Second
-			fmt.Printf("%s %3d:%02d\n", v, int(duration.Minutes()), int(duration.Seconds())%60)
+		if playlist.Duration > time.Duration(Seconds)*time.Second {
+			duration = playlist.Duration
+			fmt.Printf("%s %3d:%02d\n", v+".mpls", int(duration.Minutes()), int(duration.Seconds())%60)
+			if len(duplicates[v]) > 0 {
+				fmt.Printf("duplicates: %s\n", strings.Join(duplicates[v], ","))
+			}
 
 			fmt.Println(strings.Join(playlist.SegmentMap, ","))
 		}
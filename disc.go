@@ -0,0 +1,442 @@
+package mpls
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"timmy.narnian.us/mpls/clpi"
+	"timmy.narnian.us/mpls/index"
+	"timmy.narnian.us/mpls/movieobject"
+)
+
+// ErrNotFound is returned by Disc when a file does not exist in the BDMV tree
+var ErrNotFound = errors.New("not found")
+
+// Disc is a BDMV tree opened from the root of a disc.
+// Files are read the first time they are needed and cached, a Disc is not safe for concurrent use
+type Disc struct {
+	Root string // directory containing BDMV
+
+	files        map[string]map[string]string // directory -> name without extension -> file name
+	playlists    map[string]*MPLS
+	clips        map[string]*clpi.CLPI
+	errs         map[string]error
+	streams      map[string]int64
+	index        *index.Index
+	movieObjects *movieobject.MOBJ
+}
+
+// OpenDisc opens the BDMV tree at root, root may be the BDMV directory itself
+func OpenDisc(root string) (*Disc, error) {
+	if strings.EqualFold(filepath.Base(filepath.Clean(root)), "BDMV") {
+		root = filepath.Dir(filepath.Clean(root))
+	}
+
+	info, err := os.Stat(filepath.Join(root, "BDMV"))
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", filepath.Join(root, "BDMV"))
+	}
+
+	return &Disc{
+		Root:      root,
+		files:     map[string]map[string]string{},
+		playlists: map[string]*MPLS{},
+		clips:     map[string]*clpi.CLPI{},
+		errs:      map[string]error{},
+	}, nil
+}
+
+// list returns the files in BDMV/dir with extension ext keyed by their name without the extension.
+// Extensions are matched case insensitively
+func (d *Disc) list(dir, ext string) (map[string]string, error) {
+	key := dir + ext
+	if files, ok := d.files[key]; ok {
+		return files, nil
+	}
+
+	f, err := os.Open(filepath.Join(d.Root, "BDMV", dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			d.files[key] = map[string]string{}
+			return d.files[key], nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(0)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			files[strings.TrimSuffix(name, filepath.Ext(name))] = name
+		}
+	}
+	d.files[key] = files
+	return files, nil
+}
+
+// path returns the path of the file name in BDMV/dir with extension ext
+func (d *Disc) path(dir, ext, name string) (string, error) {
+	files, err := d.list(dir, ext)
+	if err != nil {
+		return "", err
+	}
+	file, ok := files[baseName(name)]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, filepath.Join("BDMV", dir, baseName(name)+ext))
+	}
+	return filepath.Join(d.Root, "BDMV", dir, file), nil
+}
+
+// baseName strips the directory and extension from name so that "00042", "00042.clpi" and "00042.m2ts" are the same
+func baseName(name string) string {
+	name = filepath.Base(name)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+func sortedNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PlaylistNames returns the names of the playlists in BDMV/PLAYLIST without their extension, sorted
+func (d *Disc) PlaylistNames() ([]string, error) {
+	files, err := d.list("PLAYLIST", ".mpls")
+	if err != nil {
+		return nil, err
+	}
+	return sortedNames(files), nil
+}
+
+// Playlist returns the parsed playlist name e.g. "00001".
+// If the playlist parsed with warnings they are in MPLS.Warnings
+func (d *Disc) Playlist(name string) (*MPLS, error) {
+	name = baseName(name)
+	if playlist, ok := d.playlists[name]; ok {
+		return playlist, nil
+	}
+	if err, ok := d.errs["PLAYLIST/"+name]; ok {
+		return nil, err
+	}
+
+	playlist, err := d.parsePlaylist(name)
+	if err != nil {
+		d.errs["PLAYLIST/"+name] = err
+		return nil, err
+	}
+	d.playlists[name] = playlist
+	return playlist, nil
+}
+
+func (d *Disc) parsePlaylist(name string) (*MPLS, error) {
+	path, err := d.path("PLAYLIST", ".mpls", name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	playlist, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &playlist, nil
+}
+
+// Playlists parses every playlist on the disc keyed by name. Playlists that fail to parse
+// are left out and their errors returned in errs
+func (d *Disc) Playlists() (playlists map[string]*MPLS, errs map[string]error, err error) {
+	names, err := d.PlaylistNames()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	playlists = make(map[string]*MPLS, len(names))
+	errs = map[string]error{}
+	for _, name := range names {
+		playlist, err := d.Playlist(name)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+		playlists[name] = playlist
+	}
+	return playlists, errs, nil
+}
+
+// ClipNames returns the names of the clip info files in BDMV/CLIPINF without their extension, sorted
+func (d *Disc) ClipNames() ([]string, error) {
+	files, err := d.list("CLIPINF", ".clpi")
+	if err != nil {
+		return nil, err
+	}
+	return sortedNames(files), nil
+}
+
+// Clip returns the parsed clip info name e.g. "00042"
+func (d *Disc) Clip(name string) (*clpi.CLPI, error) {
+	name = baseName(name)
+	if clip, ok := d.clips[name]; ok {
+		return clip, nil
+	}
+	if err, ok := d.errs["CLIPINF/"+name]; ok {
+		return nil, err
+	}
+
+	clip, err := d.parseClip(name)
+	if err != nil {
+		d.errs["CLIPINF/"+name] = err
+		return nil, err
+	}
+	d.clips[name] = clip
+	return clip, nil
+}
+
+func (d *Disc) parseClip(name string) (*clpi.CLPI, error) {
+	path, err := d.path("CLIPINF", ".clpi", name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	clip, err := clpi.Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &clip, nil
+}
+
+// PlaylistClips returns the clip info for each entry of the playlist's SegmentMap, in the same order
+func (d *Disc) PlaylistClips(name string) ([]*clpi.CLPI, error) {
+	playlist, err := d.Playlist(name)
+	if err != nil {
+		return nil, err
+	}
+
+	clips := make([]*clpi.CLPI, 0, len(playlist.SegmentMap))
+	for _, segment := range playlist.SegmentMap {
+		clip, err := d.Clip(segment)
+		if err != nil {
+			return nil, err
+		}
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// Streams returns the size in bytes of every stream file in BDMV/STREAM keyed by name without the extension
+func (d *Disc) Streams() (map[string]int64, error) {
+	if d.streams != nil {
+		return d.streams, nil
+	}
+
+	files, err := d.list("STREAM", ".m2ts")
+	if err != nil {
+		return nil, err
+	}
+
+	streams := make(map[string]int64, len(files))
+	for name, file := range files {
+		info, err := os.Stat(filepath.Join(d.Root, "BDMV", "STREAM", file))
+		if err != nil {
+			return nil, err
+		}
+		streams[name] = info.Size()
+	}
+	d.streams = streams
+	return streams, nil
+}
+
+// StreamSize returns the size in bytes of the stream file name e.g. "00042"
+func (d *Disc) StreamSize(name string) (int64, error) {
+	streams, err := d.Streams()
+	if err != nil {
+		return 0, err
+	}
+	size, ok := streams[baseName(name)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, filepath.Join("BDMV", "STREAM", baseName(name)+".m2ts"))
+	}
+	return size, nil
+}
+
+// PlaylistSize returns the total size in bytes of the stream files of the playlist's SegmentMap.
+// A clip used more than once is counted each time
+func (d *Disc) PlaylistSize(name string) (int64, error) {
+	playlist, err := d.Playlist(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, segment := range playlist.SegmentMap {
+		size, err := d.StreamSize(segment)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// PlaylistsUsingClip returns the sorted names of the playlists that reference clip in a PlayItem,
+// angle or SubPlayItem. Playlists that fail to parse are skipped
+func (d *Disc) PlaylistsUsingClip(clip string) ([]string, error) {
+	clip = baseName(clip)
+
+	names, err := d.PlaylistNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var using []string
+	for _, name := range names {
+		playlist, err := d.Playlist(name)
+		if err != nil {
+			continue
+		}
+		for _, c := range playlist.Clips() {
+			if c == clip {
+				using = append(using, name)
+				break
+			}
+		}
+	}
+	return using, nil
+}
+
+// Index returns the parsed BDMV/index.bdmv
+func (d *Disc) Index() (*index.Index, error) {
+	if d.index != nil {
+		return d.index, nil
+	}
+	if err, ok := d.errs["index.bdmv"]; ok {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(d.Root, "BDMV", "index.bdmv"))
+	if err != nil {
+		d.errs["index.bdmv"] = err
+		return nil, err
+	}
+	defer file.Close()
+
+	idx, err := index.Parse(file)
+	if err != nil {
+		d.errs["index.bdmv"] = err
+		return nil, err
+	}
+	d.index = &idx
+	return d.index, nil
+}
+
+// MovieObjects returns the parsed BDMV/MovieObject.bdmv
+func (d *Disc) MovieObjects() (*movieobject.MOBJ, error) {
+	if d.movieObjects != nil {
+		return d.movieObjects, nil
+	}
+	if err, ok := d.errs["MovieObject.bdmv"]; ok {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(d.Root, "BDMV", "MovieObject.bdmv"))
+	if err != nil {
+		d.errs["MovieObject.bdmv"] = err
+		return nil, err
+	}
+	defer file.Close()
+
+	mobj, err := movieobject.Parse(file)
+	if err != nil {
+		d.errs["MovieObject.bdmv"] = err
+		return nil, err
+	}
+	d.movieObjects = &mobj
+	return d.movieObjects, nil
+}
+
+// TitlePlaylists returns the names of the playlists played by the numbered title n, counting from 1.
+// Only HDMV titles playing playlists through immediate PlayPL commands can be resolved,
+// BD-J titles return no playlists
+func (d *Disc) TitlePlaylists(n int) ([]string, error) {
+	idx, err := d.Index()
+	if err != nil {
+		return nil, err
+	}
+	title, ok := idx.Title(n)
+	if !ok {
+		return nil, fmt.Errorf("%w: title %d", ErrNotFound, n)
+	}
+	if !title.IsHDMV() {
+		return nil, nil
+	}
+
+	mobj, err := d.MovieObjects()
+	if err != nil {
+		return nil, err
+	}
+	if int(title.MovieObjectID) >= len(mobj.Objects) {
+		return nil, fmt.Errorf("%w: movie object %d of title %d", ErrNotFound, title.MovieObjectID, n)
+	}
+
+	var names []string
+	for _, pl := range mobj.Objects[title.MovieObjectID].Playlists() {
+		names = append(names, fmt.Sprintf("%05d", pl))
+	}
+	return names, nil
+}
+
+// Clips returns the unique clips referenced by the playlist's PlayItems, angles and SubPlayItems
+// in the order they are first referenced
+func (mpls *MPLS) Clips() []string {
+	var (
+		clips []string
+		seen  = map[string]bool{}
+	)
+	add := func(clip CLPI) {
+		if !seen[clip.ClipFile] {
+			seen[clip.ClipFile] = true
+			clips = append(clips, clip.ClipFile)
+		}
+	}
+
+	for _, playitem := range mpls.Playlist.PlayItems {
+		add(playitem.Clpi)
+		for _, angle := range playitem.Angles {
+			add(angle)
+		}
+	}
+	for _, subpaths := range [][]SubPath{mpls.Playlist.SubPaths, mpls.ExtensionData.SubPaths} {
+		for _, subpath := range subpaths {
+			for _, subplayitem := range subpath.SubPlayItems {
+				add(subplayitem.Clpi)
+				for _, angle := range subplayitem.Angles {
+					add(angle)
+				}
+			}
+		}
+	}
+	return clips
+}
@@ -0,0 +1,20 @@
+package mpls
+
+// DolbyVisionELPID is the PID Dolby Vision enhancement layers are muxed with
+const DolbyVisionELPID = 0x1015
+
+// HasDolbyVisionEL reports whether the playlist carries a Dolby Vision enhancement layer
+func (mpls *MPLS) HasDolbyVisionEL() bool {
+	return mpls.hasVideoLayer(dolbyVisionEntries, SPTDolbyVisionEL)
+}
+
+// DolbyVisionEL returns the enhancement layer of every main path PlayItem that has one.
+// The layers are found through the Dolby Vision entries of the STN table of each PlayItem,
+// PlayItems without entries fall back to the Dolby Vision SubPaths with DolbyVisionELPID
+func (mpls *MPLS) DolbyVisionEL() []VideoLayer {
+	return mpls.videoLayers(dolbyVisionEntries, mpls.Playlist.SubPaths, SPTDolbyVisionEL, DolbyVisionELPID)
+}
+
+func dolbyVisionEntries(stnt STNTable) []StreamEntry {
+	return streamEntries(stnt.DolbyVisionStreams)
+}
@@ -0,0 +1,140 @@
+package mpls
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DuplicateGroup is a set of semantically identical playlists.
+// Canonical is the lowest numbered playlist of the group and is also in Playlists
+type DuplicateGroup struct {
+	Canonical string
+	Playlists []string
+}
+
+// Duplicates returns the playlists of the group other than Canonical
+func (g DuplicateGroup) Duplicates() []string {
+	return g.Playlists[1:]
+}
+
+// GroupDuplicates groups playlists keyed by name that play the same clips with the same streams.
+// The in and out times of matching PlayItems may differ by up to tolerance.
+// Every playlist is in exactly one group, groups are sorted by their canonical playlist
+func GroupDuplicates(playlists map[string]*MPLS, tolerance Ticks) []DuplicateGroup {
+	var (
+		names  = make([]string, 0, len(playlists))
+		groups []DuplicateGroup
+	)
+
+	for name := range playlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	signatures := make(map[string][]string, len(playlists))
+	for _, name := range names {
+		signatures[name] = playlists[name].streamSignature()
+	}
+
+names:
+	for _, name := range names {
+		for i, group := range groups {
+			canonical := group.Canonical
+			if samePlayItems(playlists[canonical], playlists[name], tolerance) && equalStrings(signatures[canonical], signatures[name]) {
+				groups[i].Playlists = append(groups[i].Playlists, name)
+				continue names
+			}
+		}
+		groups = append(groups, DuplicateGroup{
+			Canonical: name,
+			Playlists: []string{name},
+		})
+	}
+	return groups
+}
+
+// GroupDuplicates groups the playlists of the disc, see GroupDuplicates
+func (d *Disc) GroupDuplicates(tolerance Ticks) ([]DuplicateGroup, error) {
+	playlists, _, err := d.Playlists()
+	if err != nil {
+		return nil, err
+	}
+	return GroupDuplicates(playlists, tolerance), nil
+}
+
+// samePlayItems reports whether a and b play the same clips and angles with in and out times within tolerance
+func samePlayItems(a, b *MPLS, tolerance Ticks) bool {
+	if len(a.Playlist.PlayItems) != len(b.Playlist.PlayItems) {
+		return false
+	}
+	for i, pa := range a.Playlist.PlayItems {
+		pb := b.Playlist.PlayItems[i]
+		if pa.Clpi != pb.Clpi || len(pa.Angles) != len(pb.Angles) {
+			return false
+		}
+		for j := range pa.Angles {
+			if pa.Angles[j] != pb.Angles[j] {
+				return false
+			}
+		}
+		if diff(pa.InTime, pb.InTime) > tolerance || diff(pa.OutTime, pb.OutTime) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// streamSignature describes every stream of every PlayItem, playlists with the same signature have the same streams
+func (mpls *MPLS) streamSignature() []string {
+	var signature []string
+	for i, playitem := range mpls.Playlist.PlayItems {
+		playitem.StreamTable.eachStream(func(kind StreamKind, stream PrimaryStream) {
+			signature = append(signature, fmt.Sprintf("%d %s %d/%d/%d %#x %d %d %d %s",
+				i, kind, stream.Type, stream.SubPathID, stream.SubClipID, stream.PID,
+				stream.Encoding, stream.Format, stream.Rate, stream.Language))
+		})
+	}
+	return signature
+}
+
+// eachStream calls f with every stream of the table in the order of mapStreams without modifying the table
+func (stnt *STNTable) eachStream(f func(kind StreamKind, stream PrimaryStream)) {
+	each := func(kind StreamKind, streams []PrimaryStream) {
+		for _, stream := range streams {
+			f(kind, stream)
+		}
+	}
+
+	each(StreamPrimaryVideo, stnt.PrimaryVideoStreams)
+	each(StreamPrimaryAudio, stnt.PrimaryAudioStreams)
+	each(StreamPrimaryPG, stnt.PrimaryPGStreams)
+	each(StreamPIPPG, stnt.PIPPGStreams)
+	each(StreamPrimaryIG, stnt.PrimaryIGStreams)
+	for _, stream := range stnt.SecondaryAudioStreams {
+		f(StreamSecondaryAudio, stream.PrimaryStream)
+	}
+	for _, stream := range stnt.SecondaryVideoStreams {
+		f(StreamSecondaryVideo, stream.PrimaryStream)
+	}
+	each(StreamDolbyVision, stnt.DolbyVisionStreams)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diff returns the distance between a and b
+func diff(a, b Ticks) Ticks {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
@@ -0,0 +1,58 @@
+package mpls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupDuplicates(t *testing.T) {
+	shifted := editPlaylist()
+	shifted.Playlist.PlayItems[1].OutTime += 20
+	outside := editPlaylist()
+	outside.Playlist.PlayItems[1].InTime -= 30
+	language := editPlaylist()
+	language.Playlist.PlayItems[2].StreamTable.PrimaryPGStreams[0].Language = "fre"
+	pid := editPlaylist()
+	pid.Playlist.PlayItems[0].StreamTable.PrimaryVideoStreams[0].PID = 0x1012
+	playlists := map[string]*MPLS{
+		"00001": editPlaylist(),
+		"00002": shifted,
+		"00003": outside,
+		"00004": language,
+		"00005": pid,
+		"00006": editPlaylist(),
+	}
+
+	groups := GroupDuplicates(playlists, 25)
+	want := []DuplicateGroup{
+		{Canonical: "00001", Playlists: []string{"00001", "00002", "00006"}},
+		{Canonical: "00003", Playlists: []string{"00003"}},
+		{Canonical: "00004", Playlists: []string{"00004"}},
+		{Canonical: "00005", Playlists: []string{"00005"}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %+v, want %+v", groups, want)
+	}
+	if duplicates := groups[0].Duplicates(); !reflect.DeepEqual(duplicates, []string{"00002", "00006"}) {
+		t.Errorf("duplicates of 00001 = %v", duplicates)
+	}
+
+	// within a larger tolerance the moved in time matches as well
+	if groups = GroupDuplicates(playlists, 30); len(groups[0].Playlists) != 4 {
+		t.Errorf("groups with a tolerance of 30 = %+v, want 00003 in the first group", groups)
+	}
+}
+
+func TestStreamSignatureLeavesTable(t *testing.T) {
+	mpls := editPlaylist()
+	stnt := &mpls.Playlist.PlayItems[0].StreamTable
+	stnt.PrimaryVideoStreamCount = 7
+	video := stnt.PrimaryVideoStreams
+
+	if signature := mpls.streamSignature(); len(signature) != 6 {
+		t.Errorf("signature = %q, want 2 streams for each of the 3 play items", signature)
+	}
+	if stnt.PrimaryVideoStreamCount != 7 || &stnt.PrimaryVideoStreams[0] != &video[0] {
+		t.Error("streamSignature modified the stream table")
+	}
+}
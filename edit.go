@@ -0,0 +1,797 @@
+package mpls
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// StreamKind identifies which list of an STNTable a stream belongs to
+type StreamKind int
+
+// Stream kinds
+const (
+	StreamPrimaryVideo StreamKind = iota
+	StreamPrimaryAudio
+	StreamPrimaryPG
+	StreamPrimaryIG
+	StreamSecondaryAudio
+	StreamSecondaryVideo
+	StreamPIPPG
+	StreamDolbyVision
+)
+
+func (sk StreamKind) String() string {
+	switch sk {
+	case StreamPrimaryVideo:
+		return "primary video"
+	case StreamPrimaryAudio:
+		return "primary audio"
+	case StreamPrimaryPG:
+		return "presentation graphics"
+	case StreamPrimaryIG:
+		return "interactive graphics"
+	case StreamSecondaryAudio:
+		return "secondary audio"
+	case StreamSecondaryVideo:
+		return "secondary video"
+	case StreamPIPPG:
+		return "picture in picture presentation graphics"
+	case StreamDolbyVision:
+		return "Dolby Vision enhancement layer"
+	}
+	return fmt.Sprintf("StreamKind(%d)", int(sk))
+}
+
+// RemovePlayItem removes the PlayItem at index.
+// Marks on the PlayItem and SubPlayItems of synchronous SubPaths synchronized to it are removed with it,
+// the PlayItem after it is no longer seamlessly connected
+func (mpls *MPLS) RemovePlayItem(index int) error {
+	if index < 0 || index >= len(mpls.Playlist.PlayItems) {
+		return fmt.Errorf("mpls: %w: play item %d out of range", ErrInvalidValue, index)
+	}
+
+	order := make([]int, 0, len(mpls.Playlist.PlayItems)-1)
+	for i := range mpls.Playlist.PlayItems {
+		if i != index {
+			order = append(order, i)
+		}
+	}
+	return mpls.ReorderPlayItems(order)
+}
+
+// ReorderPlayItems rearranges the PlayItems so that PlayItems[i] is the old PlayItems[order[i]].
+// PlayItems missing from order are removed.
+// Marks, SubPlayItems of synchronous SubPaths and PiP metadata follow the PlayItem they refer to,
+// a SubPath left without SubPlayItems is removed along with the streams that come from it.
+// An extension SubPath left without SubPlayItems takes the STN_table_SS with it if a dependent view came from it.
+// A PlayItem that no longer follows the PlayItem it followed is no longer seamlessly connected
+func (mpls *MPLS) ReorderPlayItems(order []int) error {
+	var (
+		newIndex   = make([]int, len(mpls.Playlist.PlayItems))
+		playitems  = make([]PlayItem, 0, len(order))
+		marks      = make([]Mark, 0, len(mpls.MarkPlaylist.Marks))
+		removed    []int
+		extRemoved []int
+	)
+
+	if len(order) == 0 {
+		return fmt.Errorf("mpls: %w: a playlist needs at least one play item", ErrInvalidValue)
+	}
+
+	for i := range newIndex {
+		newIndex[i] = -1
+	}
+	for i, old := range order {
+		if old < 0 || old >= len(newIndex) || newIndex[old] != -1 {
+			return fmt.Errorf("mpls: %w: play item %d is out of range or repeated", ErrInvalidValue, old)
+		}
+		newIndex[old] = i
+	}
+
+	for i, old := range order {
+		playitem := mpls.Playlist.PlayItems[old]
+		if (i == 0 && old != 0) || (i > 0 && order[i-1] != old-1) {
+			playitem.breakConnection()
+		}
+		playitems = append(playitems, playitem)
+	}
+	mpls.Playlist.PlayItems = playitems
+
+	for _, mark := range mpls.MarkPlaylist.Marks {
+		if int(mark.PlayItemRef) >= len(newIndex) || newIndex[mark.PlayItemRef] == -1 {
+			continue
+		}
+		mark.PlayItemRef = uint16(newIndex[mark.PlayItemRef])
+		marks = append(marks, mark)
+	}
+	sort.SliceStable(marks, func(i, j int) bool {
+		if marks[i].PlayItemRef != marks[j].PlayItemRef {
+			return marks[i].PlayItemRef < marks[j].PlayItemRef
+		}
+		return marks[i].Time < marks[j].Time
+	})
+	mpls.MarkPlaylist.Marks = marks
+
+	for i := range mpls.Playlist.SubPaths {
+		sp := &mpls.Playlist.SubPaths[i]
+		if len(sp.SubPlayItems) > 0 && sp.remap(newIndex) == 0 {
+			removed = append(removed, i)
+		}
+	}
+	for i := range mpls.ExtensionData.SubPaths {
+		sp := &mpls.ExtensionData.SubPaths[i]
+		if len(sp.SubPlayItems) > 0 && sp.remap(newIndex) == 0 {
+			extRemoved = append(extRemoved, i)
+		}
+	}
+	mpls.removeSubPaths(removed)
+	mpls.removeExtensionSubPaths(extRemoved)
+
+	mpls.ExtensionData.mapPiP(func(pm *PiPMetadata) bool {
+		if int(pm.PlayItemRef) >= len(newIndex) || newIndex[pm.PlayItemRef] == -1 {
+			return false
+		}
+		pm.PlayItemRef = uint16(newIndex[pm.PlayItemRef])
+		return true
+	})
+
+	mpls.update()
+	return nil
+}
+
+// TrimPlayItem sets the InTime and OutTime of the PlayItem at index.
+// Marks before in are moved to in and marks at or after out are removed, mark durations are cut at out.
+// SubPlayItems of synchronous SubPaths that covered the whole PlayItem are trimmed with it,
+// the others are clipped to the part of the playlist timeline that is still played,
+// split if the cut falls inside them and removed if none of it is played.
+// A SubPath left without SubPlayItems is removed along with the streams that come from it.
+// An extension SubPath left without SubPlayItems takes the STN_table_SS with it if a dependent view came from it.
+// A changed bound is no longer seamlessly connected to the neighbouring PlayItem
+func (mpls *MPLS) TrimPlayItem(index int, in, out Ticks) error {
+	var (
+		marks      = make([]Mark, 0, len(mpls.MarkPlaylist.Marks))
+		removed    []int
+		extRemoved []int
+	)
+
+	if index < 0 || index >= len(mpls.Playlist.PlayItems) {
+		return fmt.Errorf("mpls: %w: play item %d out of range", ErrInvalidValue, index)
+	}
+	if in >= out {
+		return fmt.Errorf("mpls: %w: in time %d is not before out time %d", ErrInvalidValue, in, out)
+	}
+
+	pi := &mpls.Playlist.PlayItems[index]
+	trim := playItemTrim{
+		playitems: mpls.Playlist.PlayItems,
+		index:     index,
+		oldIn:     pi.InTime,
+		oldOut:    pi.OutTime,
+		old:       mpls.playItemOffsets(),
+	}
+	pi.InTime, pi.OutTime = in, out
+	trim.offsets = mpls.playItemOffsets()
+	if in != trim.oldIn {
+		pi.breakConnection()
+	}
+	if out != trim.oldOut && index+1 < len(mpls.Playlist.PlayItems) {
+		mpls.Playlist.PlayItems[index+1].breakConnection()
+	}
+
+	for _, mark := range mpls.MarkPlaylist.Marks {
+		if int(mark.PlayItemRef) == index {
+			if mark.Time >= out {
+				continue
+			}
+			if mark.Time < in {
+				if mark.Time+mark.Duration > in {
+					mark.Duration = mark.Time + mark.Duration - in
+				} else {
+					mark.Duration = 0
+				}
+				mark.Time = in
+			}
+			if mark.Time+mark.Duration > out {
+				mark.Duration = out - mark.Time
+			}
+			if len(marks) > 0 {
+				last := marks[len(marks)-1]
+				if last.PlayItemRef == mark.PlayItemRef && last.Type == mark.Type && last.Time == mark.Time {
+					continue
+				}
+			}
+		}
+		marks = append(marks, mark)
+	}
+	mpls.MarkPlaylist.Marks = marks
+
+	for i := range mpls.Playlist.SubPaths {
+		sp := &mpls.Playlist.SubPaths[i]
+		if len(sp.SubPlayItems) > 0 && sp.trim(trim) == 0 {
+			removed = append(removed, i)
+		}
+	}
+	for i := range mpls.ExtensionData.SubPaths {
+		sp := &mpls.ExtensionData.SubPaths[i]
+		if len(sp.SubPlayItems) > 0 && sp.trim(trim) == 0 {
+			extRemoved = append(extRemoved, i)
+		}
+	}
+	mpls.removeSubPaths(removed)
+	mpls.removeExtensionSubPaths(extRemoved)
+
+	mpls.update()
+	return nil
+}
+
+// Concat appends the PlayItems, marks and SubPaths of other to the playlist.
+// SubPaths are merged pairwise when both playlists have the same SubPath types in the same order,
+// otherwise the SubPaths of other are added after the existing ones.
+// Extension sub-paths must match. The STN_table_SS of each PlayItem comes along with it,
+// other extension data of other is not copied.
+// The first PlayItem of other is not seamlessly connected to the last PlayItem of the playlist
+func (mpls *MPLS) Concat(other *MPLS) error {
+	var (
+		offset   = len(mpls.Playlist.PlayItems)
+		merge    = sameSubPathTypes(mpls.Playlist.SubPaths, other.Playlist.SubPaths)
+		subpaths = len(mpls.Playlist.SubPaths)
+	)
+
+	if !sameSubPathTypes(mpls.ExtensionData.SubPaths, other.ExtensionData.SubPaths) {
+		return fmt.Errorf("mpls: %w: extension sub-paths of the playlists differ", ErrInvalidValue)
+	}
+
+	for i, playitem := range other.Playlist.PlayItems {
+		if i == 0 {
+			playitem.breakConnection()
+		}
+		playitem.StreamTable.mapStreams(func(kind StreamKind, stream *PrimaryStream) bool {
+			if !merge && stream.StreamEntry.fromSubPath() {
+				stream.StreamEntry.SubPathID += byte(subpaths)
+			}
+			return true
+		})
+		mpls.Playlist.PlayItems = append(mpls.Playlist.PlayItems, playitem)
+	}
+
+	for _, mark := range other.MarkPlaylist.Marks {
+		mark.PlayItemRef += uint16(offset)
+		mpls.MarkPlaylist.Marks = append(mpls.MarkPlaylist.Marks, mark)
+	}
+
+	if merge {
+		mergeSubPaths(mpls.Playlist.SubPaths, other.Playlist.SubPaths, offset)
+	} else {
+		for _, sp := range other.Playlist.SubPaths {
+			sp.SubPlayItems = offsetSubPlayItems(nil, sp.SubPlayItems, offset)
+			mpls.Playlist.SubPaths = append(mpls.Playlist.SubPaths, sp)
+		}
+	}
+	mergeSubPaths(mpls.ExtensionData.SubPaths, other.ExtensionData.SubPaths, offset)
+
+	mpls.update()
+	return nil
+}
+
+// DropStreams removes the streams for which keep returns false from the STNTable of every PlayItem.
+// PiP metadata of removed secondary video streams is removed with them
+func (mpls *MPLS) DropStreams(keep func(kind StreamKind, stream PrimaryStream) bool) {
+	for i := range mpls.Playlist.PlayItems {
+		mpls.mapStreams(i, func(kind StreamKind, stream *PrimaryStream) bool {
+			return keep(kind, *stream)
+		})
+	}
+}
+
+// DropStreams removes the streams for which keep returns false.
+// References from secondary audio and video streams are renumbered to match,
+// the PiP metadata of the playlist is not, see MPLS.DropStreams
+func (stnt *STNTable) DropStreams(keep func(kind StreamKind, stream PrimaryStream) bool) {
+	stnt.mapStreams(func(kind StreamKind, stream *PrimaryStream) bool {
+		return keep(kind, *stream)
+	})
+}
+
+// mapStreams maps the streams of the PlayItem at index with f, see STNTable.mapStreams,
+// and renumbers the PiP metadata of its secondary video streams
+func (mpls *MPLS) mapStreams(index int, f func(kind StreamKind, stream *PrimaryStream) bool) {
+	secondaryVideo := mpls.Playlist.PlayItems[index].StreamTable.mapStreams(f)
+	mpls.ExtensionData.mapPiP(func(pm *PiPMetadata) bool {
+		if int(pm.PlayItemRef) != index || int(pm.SecondaryVideoRef) >= len(secondaryVideo) {
+			return true
+		}
+		if secondaryVideo[pm.SecondaryVideoRef] == -1 {
+			return false
+		}
+		pm.SecondaryVideoRef = byte(secondaryVideo[pm.SecondaryVideoRef])
+		return true
+	})
+}
+
+// mapPiP keeps the PiP metadata blocks for which f returns true, f may modify the block
+func (ed *ExtensionData) mapPiP(f func(pm *PiPMetadata) bool) {
+	var kept []PiPMetadata
+	for _, pm := range ed.PiPMetadata {
+		if f(&pm) {
+			kept = append(kept, pm)
+		}
+	}
+	ed.PiPMetadata = kept
+}
+
+// mapStreams rebuilds every stream list keeping the streams for which f returns true.
+// f may modify the stream, the lists of the STNTable are never modified in place.
+// It returns the new index of every secondary video stream, -1 if removed
+func (stnt *STNTable) mapStreams(f func(kind StreamKind, stream *PrimaryStream) bool) []int {
+	var (
+		video          []int
+		audio          []int
+		pg             []int
+		pip            []int
+		ig             []int
+		secondaryAudio []int
+		secondaryVideo []int
+	)
+
+	stnt.PrimaryVideoStreams, video = mapPrimaryStreams(stnt.PrimaryVideoStreams, StreamPrimaryVideo, f)
+	stnt.PrimaryAudioStreams, audio = mapPrimaryStreams(stnt.PrimaryAudioStreams, StreamPrimaryAudio, f)
+	stnt.PrimaryPGStreams, pg = mapPrimaryStreams(stnt.PrimaryPGStreams, StreamPrimaryPG, f)
+	stnt.PIPPGStreams, pip = mapPrimaryStreams(stnt.PIPPGStreams, StreamPIPPG, f)
+
+	// PiP PG streams are numbered after the primary PG streams
+	for _, i := range pip {
+		if i >= 0 {
+			i += len(stnt.PrimaryPGStreams)
+		}
+		pg = append(pg, i)
+	}
+	stnt.PrimaryIGStreams, ig = mapPrimaryStreams(stnt.PrimaryIGStreams, StreamPrimaryIG, f)
+	stnt.SS.remap(video, pg, ig)
+
+	secondaryAudio = make([]int, len(stnt.SecondaryAudioStreams))
+	sas := make([]SecondaryAudioStream, 0, len(stnt.SecondaryAudioStreams))
+	for i, stream := range stnt.SecondaryAudioStreams {
+		secondaryAudio[i] = -1
+		if !f(StreamSecondaryAudio, &stream.PrimaryStream) {
+			continue
+		}
+		secondaryAudio[i] = len(sas)
+		stream.ExtraAttributes = stream.ExtraAttributes.remap(audio)
+		sas = append(sas, stream)
+	}
+	stnt.SecondaryAudioStreams = sas
+
+	secondaryVideo = make([]int, len(stnt.SecondaryVideoStreams))
+	svs := make([]SecondaryVideoStream, 0, len(stnt.SecondaryVideoStreams))
+	for i, stream := range stnt.SecondaryVideoStreams {
+		secondaryVideo[i] = -1
+		if !f(StreamSecondaryVideo, &stream.PrimaryStream) {
+			continue
+		}
+		secondaryVideo[i] = len(svs)
+		stream.ExtraAttributes = stream.ExtraAttributes.remap(secondaryAudio)
+		stream.PGStream = stream.PGStream.remap(pg)
+		svs = append(svs, stream)
+	}
+	stnt.SecondaryVideoStreams = svs
+
+	stnt.DolbyVisionStreams, _ = mapPrimaryStreams(stnt.DolbyVisionStreams, StreamDolbyVision, f)
+
+	stnt.update()
+	return secondaryVideo
+}
+
+// mapPrimaryStreams returns the streams for which f returns true and the new index of every stream, -1 if removed
+func mapPrimaryStreams(streams []PrimaryStream, kind StreamKind, f func(kind StreamKind, stream *PrimaryStream) bool) ([]PrimaryStream, []int) {
+	var (
+		index = make([]int, len(streams))
+		kept  = make([]PrimaryStream, 0, len(streams))
+	)
+
+	for i, stream := range streams {
+		index[i] = -1
+		if f(kind, &stream) {
+			index[i] = len(kept)
+			kept = append(kept, stream)
+		}
+	}
+	return kept, index
+}
+
+// remap returns a copy with the stream references renumbered by index.
+// References to removed streams are dropped, a nil index keeps every reference
+func (ss SecondaryStream) remap(index []int) SecondaryStream {
+	ids := make([]byte, 0, len(ss.StreamIDs))
+	for _, id := range ss.StreamIDs {
+		switch {
+		case index == nil || int(id) >= len(index):
+			ids = append(ids, id)
+		case index[id] >= 0:
+			ids = append(ids, byte(index[id]))
+		}
+	}
+	ss.StreamIDs = ids
+	ss.RefrenceEntryCount = byte(len(ids))
+	return ss
+}
+
+// remap drops the stereoscopic streams whose STNTable stream was removed, index holds the new index of every stream.
+// The lists of ss are never modified in place
+func (ss *STNTableSS) remap(video, pg, ig []int) {
+	var dvs []DependentViewStream
+	for i, stream := range ss.DependentViewStreams {
+		if i >= len(video) || video[i] >= 0 {
+			dvs = append(dvs, stream)
+		}
+	}
+	ss.DependentViewStreams = dvs
+	ss.PGStreams = remapGraphicsSS(ss.PGStreams, pg)
+	ss.IGStreams = remapGraphicsSS(ss.IGStreams, ig)
+}
+
+// remapGraphicsSS returns the streams whose index is not -1
+func remapGraphicsSS(streams []GraphicsStreamSS, index []int) []GraphicsStreamSS {
+	var kept []GraphicsStreamSS
+	for i, stream := range streams {
+		if i >= len(index) || index[i] >= 0 {
+			kept = append(kept, stream)
+		}
+	}
+	return kept
+}
+
+// fromSubPath reports whether the stream is carried by a SubPath
+func (se StreamEntry) fromSubPath() bool {
+	return se.Type >= 2 && se.Type <= 4
+}
+
+// remap renumbers the PlayItemID of every SubPlayItem of a synchronous SubPath by newIndex,
+// dropping the ones whose PlayItem was removed. SubPlayItems of asynchronous SubPaths are not tied to a PlayItem
+// and are left as they are. It returns the number of SubPlayItems left
+func (sp *SubPath) remap(newIndex []int) int {
+	if !sp.Type.IsSynchronous() {
+		return len(sp.SubPlayItems)
+	}
+
+	items := make([]SubPlayItem, 0, len(sp.SubPlayItems))
+	for _, spi := range sp.SubPlayItems {
+		if int(spi.PlayItemID) >= len(newIndex) || newIndex[spi.PlayItemID] == -1 {
+			continue
+		}
+		spi.PlayItemID = uint16(newIndex[spi.PlayItemID])
+		items = append(items, spi)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].PlayItemID != items[j].PlayItemID {
+			return items[i].PlayItemID < items[j].PlayItemID
+		}
+		return items[i].StartOfPlayitem < items[j].StartOfPlayitem
+	})
+	sp.SubPlayItems = items
+	return len(items)
+}
+
+// playItemTrim describes how TrimPlayItem changed the playlist timeline
+type playItemTrim struct {
+	playitems     []PlayItem // the PlayItems after the trim
+	index         int        // the trimmed PlayItem
+	oldIn, oldOut Ticks      // the bounds of the trimmed PlayItem before the trim
+	old, offsets  []Ticks    // playItemOffsets before and after the trim
+}
+
+// position returns where spi started on the timeline before the trim, see MPLS.SubPlayItemStart
+func (t playItemTrim) position(spi SubPlayItem) int64 {
+	in := t.playitems[spi.PlayItemID].InTime
+	if int(spi.PlayItemID) == t.index {
+		in = t.oldIn
+	}
+	position := int64(t.old[spi.PlayItemID])
+	if spi.StartOfPlayitem > in {
+		position += int64(spi.StartOfPlayitem - in)
+	}
+	return position
+}
+
+// kept returns the ranges of the timeline before the trim that are still played, in order
+func (t playItemTrim) kept() [][2]int64 {
+	var (
+		playitem = t.playitems[t.index]
+		start    = int64(t.old[t.index])
+		length   = int64(t.oldOut) - int64(t.oldIn)
+		from     = start + clamp(int64(playitem.InTime)-int64(t.oldIn), 0, length)
+		to       = start + clamp(int64(playitem.OutTime)-int64(t.oldIn), 0, length)
+		ranges   = [][2]int64{{0, start}}
+	)
+
+	for _, r := range [][2]int64{{from, to}, {int64(t.old[t.index+1]), math.MaxInt64}} {
+		switch last := &ranges[len(ranges)-1]; {
+		case r[0] >= r[1]:
+		case last[1] == r[0]:
+			last[1] = r[1]
+		default:
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// move returns where position p of a kept range of the timeline before the trim is after it
+func (t playItemTrim) move(p int64) int64 {
+	switch start, end := int64(t.old[t.index]), int64(t.old[t.index+1]); {
+	case p < start:
+		return p
+	case p >= end:
+		return p - end + int64(t.offsets[t.index+1])
+	}
+	return int64(t.offsets[t.index]) + int64(t.oldIn) + p - int64(t.old[t.index]) - int64(t.playitems[t.index].InTime)
+}
+
+// anchor returns the PlayItemID and StartOfPlayitem of a SubPlayItem starting at position p of the timeline after the trim
+func (t playItemTrim) anchor(p int64) (uint16, Ticks) {
+	i := len(t.playitems) - 1
+	for i > 0 && int64(t.offsets[i]) > p {
+		i--
+	}
+	return uint16(i), t.playitems[i].InTime + Ticks(p-int64(t.offsets[i]))
+}
+
+// trim clips the SubPlayItems of a synchronous SubPath to the timeline after t.
+// A SubPlayItem that covered the whole trimmed PlayItem is trimmed with it, the others keep the part
+// that is still played, wherever they are synchronized to. A SubPlayItem is split when the part cut
+// from the PlayItem falls inside it and dropped when none of it is played.
+// SubPlayItems of asynchronous SubPaths are left as they are. It returns the number of SubPlayItems left
+func (sp *SubPath) trim(t playItemTrim) int {
+	if !sp.Type.IsSynchronous() {
+		return len(sp.SubPlayItems)
+	}
+
+	var (
+		items = make([]SubPlayItem, 0, len(sp.SubPlayItems))
+		kept  = t.kept()
+		start = int64(t.old[t.index])
+	)
+	for _, spi := range sp.SubPlayItems {
+		id := int(spi.PlayItemID)
+		if id >= len(t.playitems) {
+			items = append(items, spi)
+			continue
+		}
+		if id == t.index && spi.InTime == t.oldIn && spi.OutTime == t.oldOut {
+			playitem := t.playitems[t.index]
+			spi.InTime, spi.OutTime = playitem.InTime, playitem.OutTime
+			if spi.StartOfPlayitem == t.oldIn {
+				spi.StartOfPlayitem = playitem.InTime
+			}
+			items = append(items, spi)
+			continue
+		}
+
+		from := t.position(spi)
+		to := from + int64(spi.Length())
+		// before the trimmed PlayItem or synchronized to a PlayItem after it, the SubPlayItem does not move
+		if id != t.index && (id > t.index || to <= start) {
+			items = append(items, spi)
+			continue
+		}
+
+		for _, r := range kept {
+			p0, p1 := from, to
+			if r[0] > p0 {
+				p0 = r[0]
+			}
+			if r[1] < p1 {
+				p1 = r[1]
+			}
+			if p0 >= p1 {
+				continue
+			}
+			piece := spi
+			piece.InTime = spi.InTime + Ticks(p0-from)
+			piece.OutTime = piece.InTime + Ticks(p1-p0)
+			piece.PlayItemID, piece.StartOfPlayitem = t.anchor(t.move(p0))
+			items = append(items, piece)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].PlayItemID != items[j].PlayItemID {
+			return items[i].PlayItemID < items[j].PlayItemID
+		}
+		return items[i].StartOfPlayitem < items[j].StartOfPlayitem
+	})
+	sp.SubPlayItems = items
+	return len(items)
+}
+
+// clamp returns v limited to lo and hi
+func clamp(v, lo, hi int64) int64 {
+	switch {
+	case v < lo:
+		return lo
+	case v > hi:
+		return hi
+	}
+	return v
+}
+
+// breakConnection sets the connection condition of a seamlessly connected PlayItem to CCNotSeamless,
+// for when the PlayItem before it changes
+func (pi *PlayItem) breakConnection() {
+	switch pi.Flags & PIFConnectionCondition {
+	case CCSeamless, CCSeamlessNoOverlap:
+		pi.Flags = pi.Flags&^PIFConnectionCondition | CCNotSeamless
+	}
+}
+
+// removeSubPaths removes the main path SubPaths at the sorted indexes in removed
+// and renumbers or drops the streams that refer to SubPaths
+func (mpls *MPLS) removeSubPaths(removed []int) {
+	if len(removed) == 0 {
+		return
+	}
+
+	var newIndex []int
+	mpls.Playlist.SubPaths, newIndex = dropSubPaths(mpls.Playlist.SubPaths, removed)
+
+	for i := range mpls.Playlist.PlayItems {
+		mpls.mapStreams(i, func(kind StreamKind, stream *PrimaryStream) bool {
+			return stream.StreamEntry.remapSubPathID(newIndex)
+		})
+	}
+}
+
+// removeExtensionSubPaths removes the extension data SubPaths at the sorted indexes in removed
+// and renumbers the dependent view streams that refer to them.
+// The STN_table_SS describes every PlayItem, so when a dependent view came from a removed SubPath
+// the STN_table_SS of every PlayItem is dropped along with its extension entry
+func (mpls *MPLS) removeExtensionSubPaths(removed []int) {
+	if len(removed) == 0 {
+		return
+	}
+
+	var (
+		newIndex     []int
+		stereoscopic = true
+	)
+	mpls.ExtensionData.SubPaths, newIndex = dropSubPaths(mpls.ExtensionData.SubPaths, removed)
+
+	for i := range mpls.Playlist.PlayItems {
+		ss := &mpls.Playlist.PlayItems[i].StreamTable.SS
+		dvs := make([]DependentViewStream, 0, len(ss.DependentViewStreams))
+		for _, stream := range ss.DependentViewStreams {
+			if !stream.StreamEntry.remapSubPathID(newIndex) {
+				stereoscopic = false
+			}
+			dvs = append(dvs, stream)
+		}
+		ss.DependentViewStreams = dvs
+	}
+	if stereoscopic {
+		return
+	}
+
+	for i := range mpls.Playlist.PlayItems {
+		mpls.Playlist.PlayItems[i].StreamTable.SS = STNTableSS{}
+	}
+	mpls.ExtensionData.removeEntry(ExtensionSTNTableSS)
+}
+
+// dropSubPaths returns subpaths without the ones at the sorted indexes in removed
+// and the new index of every SubPath, -1 if removed
+func dropSubPaths(subpaths []SubPath, removed []int) ([]SubPath, []int) {
+	var (
+		newIndex = make([]int, len(subpaths))
+		kept     = make([]SubPath, 0, len(subpaths))
+	)
+
+	for i, sp := range subpaths {
+		newIndex[i] = -1
+		if len(removed) > 0 && removed[0] == i {
+			removed = removed[1:]
+			continue
+		}
+		newIndex[i] = len(kept)
+		kept = append(kept, sp)
+	}
+	return kept, newIndex
+}
+
+// remapSubPathID renumbers the SubPathID of an entry carried by a SubPath by newIndex.
+// It returns false if the SubPath was removed
+func (se *StreamEntry) remapSubPathID(newIndex []int) bool {
+	if !se.fromSubPath() || int(se.SubPathID) >= len(newIndex) {
+		return true
+	}
+	if newIndex[se.SubPathID] == -1 {
+		return false
+	}
+	se.SubPathID = byte(newIndex[se.SubPathID])
+	return true
+}
+
+// removeEntry removes the entries with the ID id, keeping the order of the data of the others
+func (ed *ExtensionData) removeEntry(id uint32) {
+	var (
+		newIndex = make([]int, len(ed.Entries))
+		entries  = make([]ExtensionEntry, 0, len(ed.Entries))
+		order    = make([]int, 0, len(ed.order))
+	)
+
+	for i, entry := range ed.Entries {
+		newIndex[i] = -1
+		if entry.ID() != id {
+			newIndex[i] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+	for _, i := range ed.order {
+		if i < len(newIndex) && newIndex[i] != -1 {
+			order = append(order, newIndex[i])
+		}
+	}
+	ed.Entries, ed.order = entries, order
+}
+
+// sameSubPathTypes reports whether both lists have the same SubPath types in the same order
+func sameSubPathTypes(a, b []SubPath) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeSubPaths appends the SubPlayItems of every SubPath in other to the SubPath at the same index in subpaths
+func mergeSubPaths(subpaths, other []SubPath, offset int) {
+	for i := range other {
+		subpaths[i].SubPlayItems = offsetSubPlayItems(subpaths[i].SubPlayItems, other[i].SubPlayItems, offset)
+	}
+}
+
+// offsetSubPlayItems appends items to dst with offset added to their PlayItemID
+func offsetSubPlayItems(dst, items []SubPlayItem, offset int) []SubPlayItem {
+	merged := make([]SubPlayItem, 0, len(dst)+len(items))
+	merged = append(merged, dst...)
+	for _, spi := range items {
+		spi.PlayItemID += uint16(offset)
+		merged = append(merged, spi)
+	}
+	return merged
+}
+
+// update sets the count fields from the lists they count and recomputes the derived fields.
+// Len fields and section addresses are recomputed by MarshalBinary
+func (mpls *MPLS) update() {
+	mpls.Playlist.PlayItemCount = uint16(len(mpls.Playlist.PlayItems))
+	mpls.Playlist.SubPathCount = uint16(len(mpls.Playlist.SubPaths))
+	mpls.MarkPlaylist.MarkCount = uint16(len(mpls.MarkPlaylist.Marks))
+	mpls.ExtensionData.EntryCount = byte(len(mpls.ExtensionData.Entries))
+
+	for i := range mpls.Playlist.PlayItems {
+		mpls.Playlist.PlayItems[i].StreamTable.update()
+	}
+	for i := range mpls.Playlist.SubPaths {
+		mpls.Playlist.SubPaths[i].PlayItemCount = byte(len(mpls.Playlist.SubPaths[i].SubPlayItems))
+	}
+	for i := range mpls.ExtensionData.SubPaths {
+		mpls.ExtensionData.SubPaths[i].PlayItemCount = byte(len(mpls.ExtensionData.SubPaths[i].SubPlayItems))
+	}
+
+	mpls.derive()
+}
+
+// update sets the stream counts from the stream lists
+func (stnt *STNTable) update() {
+	stnt.PrimaryVideoStreamCount = byte(len(stnt.PrimaryVideoStreams))
+	stnt.PrimaryAudioStreamCount = byte(len(stnt.PrimaryAudioStreams))
+	stnt.PrimaryPGStreamCount = byte(len(stnt.PrimaryPGStreams))
+	stnt.PrimaryIGStreamCount = byte(len(stnt.PrimaryIGStreams))
+	stnt.SecondaryAudioStreamCount = byte(len(stnt.SecondaryAudioStreams))
+	stnt.SecondaryVideoStreamCount = byte(len(stnt.SecondaryVideoStreams))
+	stnt.PIPPGStreamCount = byte(len(stnt.PIPPGStreams))
+	stnt.DolbyVisionStreamCount = byte(len(stnt.DolbyVisionStreams))
+}
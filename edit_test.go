@@ -0,0 +1,530 @@
+package mpls
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// editPlaylist returns a playlist of three seamlessly connected PlayItems with marks,
+// a synchronous SubPath covering every PlayItem and a PG stream carried by it
+func editPlaylist() *MPLS {
+	mpls := &MPLS{}
+	for i, clip := range []string{"00001", "00002", "00003"} {
+		flags := uint16(CCSeamless)
+		if i == 0 {
+			flags = CCNotSeamless
+		}
+		mpls.Playlist.PlayItems = append(mpls.Playlist.PlayItems, PlayItem{
+			Flags:   flags,
+			InTime:  1000,
+			OutTime: 5000,
+			Clpi:    CLPI{ClipFile: clip, ClipID: "M2TS"},
+			StreamTable: STNTable{
+				PrimaryVideoStreams: []PrimaryStream{{
+					StreamEntry:      StreamEntry{Type: 1, PID: 0x1011},
+					StreamAttributes: StreamAttributes{Encoding: VTH264, Format: 6, Rate: 1},
+				}},
+				PrimaryPGStreams: []PrimaryStream{{
+					StreamEntry:      StreamEntry{Type: 3, PID: 0x1200, SubPathID: 0},
+					StreamAttributes: StreamAttributes{Encoding: PresentationGraphics, Language: "eng"},
+				}},
+			},
+		})
+		mpls.MarkPlaylist.Marks = append(mpls.MarkPlaylist.Marks,
+			Mark{Type: 1, PlayItemRef: uint16(i), Time: 1000, PID: 0xFFFF},
+			Mark{Type: 1, PlayItemRef: uint16(i), Time: 3000, PID: 0xFFFF, Duration: 1500},
+		)
+	}
+	mpls.Playlist.SubPaths = []SubPath{{
+		Type: SPTTextSubtitle,
+		SubPlayItems: []SubPlayItem{
+			{PlayItemID: 0, StartOfPlayitem: 1000, InTime: 0, OutTime: 4000, Clpi: CLPI{ClipFile: "00011", ClipID: "M2TS"}},
+			{PlayItemID: 1, StartOfPlayitem: 2000, InTime: 500, OutTime: 2500, Clpi: CLPI{ClipFile: "00012", ClipID: "M2TS"}},
+			{PlayItemID: 2, StartOfPlayitem: 1000, InTime: 0, OutTime: 4000, Clpi: CLPI{ClipFile: "00013", ClipID: "M2TS"}},
+		},
+	}}
+	mpls.update()
+	return mpls
+}
+
+func connectionConditions(mpls *MPLS) []uint16 {
+	var ccs []uint16
+	for _, playitem := range mpls.Playlist.PlayItems {
+		ccs = append(ccs, playitem.Flags&PIFConnectionCondition)
+	}
+	return ccs
+}
+
+func clipFiles(mpls *MPLS) []string {
+	var clips []string
+	for _, playitem := range mpls.Playlist.PlayItems {
+		clips = append(clips, playitem.Clpi.ClipFile)
+	}
+	return clips
+}
+
+func TestRemovePlayItem(t *testing.T) {
+	mpls := editPlaylist()
+	if err := mpls.RemovePlayItem(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := clipFiles(mpls), []string{"00001", "00003"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("clips = %v, want %v", got, want)
+	}
+	if got, want := connectionConditions(mpls), []uint16{CCNotSeamless, CCNotSeamless}; !reflect.DeepEqual(got, want) {
+		t.Errorf("connection conditions = %v, want %v", got, want)
+	}
+	if mpls.Playlist.PlayItemCount != 2 || mpls.MarkPlaylist.MarkCount != 4 {
+		t.Errorf("counts = %d play items, %d marks, want 2 and 4", mpls.Playlist.PlayItemCount, mpls.MarkPlaylist.MarkCount)
+	}
+	for _, mark := range mpls.MarkPlaylist.Marks {
+		if mark.PlayItemRef > 1 {
+			t.Errorf("mark %+v refers to a removed play item", mark)
+		}
+	}
+	spis := mpls.Playlist.SubPaths[0].SubPlayItems
+	if len(spis) != 2 || spis[1].PlayItemID != 1 || spis[1].Clpi.ClipFile != "00013" {
+		t.Errorf("sub play items = %+v, want 00011 and 00013 on play items 0 and 1", spis)
+	}
+
+	if err := mpls.RemovePlayItem(2); err == nil {
+		t.Error("removing a play item out of range succeeded")
+	}
+}
+
+func TestReorderPlayItems(t *testing.T) {
+	mpls := editPlaylist()
+	if err := mpls.ReorderPlayItems([]int{1, 2, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := clipFiles(mpls), []string{"00002", "00003", "00001"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("clips = %v, want %v", got, want)
+	}
+	// 00003 still follows 00002, the others have a new predecessor
+	if got, want := connectionConditions(mpls), []uint16{CCNotSeamless, CCSeamless, CCNotSeamless}; !reflect.DeepEqual(got, want) {
+		t.Errorf("connection conditions = %v, want %v", got, want)
+	}
+	for i, mark := range mpls.MarkPlaylist.Marks {
+		if int(mark.PlayItemRef) != i/2 {
+			t.Errorf("mark %d refers to play item %d, want %d", i, mark.PlayItemRef, i/2)
+		}
+	}
+	for _, spi := range mpls.Playlist.SubPaths[0].SubPlayItems {
+		if want := mpls.Playlist.PlayItems[spi.PlayItemID].Clpi.ClipFile[4:]; spi.Clpi.ClipFile[4:] != want {
+			t.Errorf("sub play item %s is on play item %d, clip %s", spi.Clpi.ClipFile, spi.PlayItemID, want)
+		}
+	}
+
+	for _, order := range [][]int{nil, {0, 0}, {3}} {
+		if err := editPlaylist().ReorderPlayItems(order); err == nil {
+			t.Errorf("order %v was accepted", order)
+		}
+	}
+}
+
+func TestReorderPlayItemsRemovesSubPaths(t *testing.T) {
+	mpls := editPlaylist()
+	mpls.Playlist.SubPaths[0].SubPlayItems = mpls.Playlist.SubPaths[0].SubPlayItems[:1]
+	if err := mpls.ReorderPlayItems([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mpls.Playlist.SubPaths) != 0 || mpls.Playlist.SubPathCount != 0 {
+		t.Errorf("sub paths = %+v, want none", mpls.Playlist.SubPaths)
+	}
+	for _, playitem := range mpls.Playlist.PlayItems {
+		if len(playitem.StreamTable.PrimaryPGStreams) != 0 || playitem.StreamTable.PrimaryPGStreamCount != 0 {
+			t.Errorf("PG streams of the removed sub path were kept: %+v", playitem.StreamTable.PrimaryPGStreams)
+		}
+	}
+}
+
+func TestTrimPlayItem(t *testing.T) {
+	mpls := editPlaylist()
+	if err := mpls.TrimPlayItem(1, 2500, 4000); err != nil {
+		t.Fatal(err)
+	}
+
+	playitem := mpls.Playlist.PlayItems[1]
+	if playitem.InTime != 2500 || playitem.OutTime != 4000 {
+		t.Errorf("play item = %d-%d, want 2500-4000", playitem.InTime, playitem.OutTime)
+	}
+	if got, want := connectionConditions(mpls), []uint16{CCNotSeamless, CCNotSeamless, CCNotSeamless}; !reflect.DeepEqual(got, want) {
+		t.Errorf("connection conditions = %v, want %v", got, want)
+	}
+
+	want := []Mark{
+		{Type: 1, PlayItemRef: 1, Time: 2500, PID: 0xFFFF},
+		{Type: 1, PlayItemRef: 1, Time: 3000, PID: 0xFFFF, Duration: 1000},
+	}
+	if got := mpls.MarkPlaylist.Marks[2:4]; !reflect.DeepEqual(got, want) {
+		t.Errorf("marks = %+v, want %+v", got, want)
+	}
+
+	// the SubPlayItem played from 2000 to 4000, it is cut to 2500
+	spi := mpls.Playlist.SubPaths[0].SubPlayItems[1]
+	if spi.StartOfPlayitem != 2500 || spi.InTime != 1000 || spi.OutTime != 2500 {
+		t.Errorf("sub play item = %d in %d-%d, want 2500 in 1000-2500", spi.StartOfPlayitem, spi.InTime, spi.OutTime)
+	}
+}
+
+func TestTrimPlayItemMarks(t *testing.T) {
+	mpls := editPlaylist()
+	if err := mpls.TrimPlayItem(0, 3500, 4000); err != nil {
+		t.Fatal(err)
+	}
+
+	// both marks move to 3500, the second is dropped as a repeat of the first
+	want := []Mark{{Type: 1, PlayItemRef: 0, Time: 3500, PID: 0xFFFF}}
+	if got := mpls.MarkPlaylist.Marks[:1]; !reflect.DeepEqual(got, want) || mpls.MarkPlaylist.Marks[1].PlayItemRef != 1 {
+		t.Errorf("marks = %+v, want %+v followed by the marks of play item 1", mpls.MarkPlaylist.Marks, want)
+	}
+	if got, want := connectionConditions(mpls), []uint16{CCNotSeamless, CCNotSeamless, CCSeamless}; !reflect.DeepEqual(got, want) {
+		t.Errorf("connection conditions = %v, want %v", got, want)
+	}
+}
+
+func TestTrimPlayItemSubPlayItems(t *testing.T) {
+	mpls := editPlaylist()
+	mpls.Playlist.SubPaths[0].SubPlayItems[1].OutTime = 1500 // plays from 2000 to 3000
+	if err := mpls.TrimPlayItem(1, 3000, 5000); err != nil {
+		t.Fatal(err)
+	}
+
+	spis := mpls.Playlist.SubPaths[0].SubPlayItems
+	if len(spis) != 2 || spis[0].PlayItemID != 0 || spis[1].PlayItemID != 2 {
+		t.Errorf("sub play items = %+v, want the one on play item 1 removed", spis)
+	}
+	if got, want := connectionConditions(mpls), []uint16{CCNotSeamless, CCNotSeamless, CCSeamless}; !reflect.DeepEqual(got, want) {
+		t.Errorf("connection conditions = %v, want %v", got, want)
+	}
+
+	// a SubPlayItem covering the whole PlayItem follows it
+	mpls.Playlist.SubPaths[0].SubPlayItems[0].InTime = 1000
+	mpls.Playlist.SubPaths[0].SubPlayItems[0].OutTime = 5000
+	if err := mpls.TrimPlayItem(0, 500, 5000); err != nil {
+		t.Fatal(err)
+	}
+	if spi := mpls.Playlist.SubPaths[0].SubPlayItems[0]; spi.StartOfPlayitem != 500 || spi.InTime != 500 || spi.OutTime != 5000 {
+		t.Errorf("sub play item = %d in %d-%d, want 500 in 500-5000", spi.StartOfPlayitem, spi.InTime, spi.OutTime)
+	}
+
+	for _, bounds := range [][2]Ticks{{2000, 2000}, {3000, 1000}} {
+		if err := mpls.TrimPlayItem(0, bounds[0], bounds[1]); err == nil {
+			t.Errorf("bounds %v were accepted", bounds)
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	mpls, other := editPlaylist(), editPlaylist()
+	if err := mpls.Concat(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(mpls.Playlist.PlayItems), 6; got != want || mpls.Playlist.PlayItemCount != 6 {
+		t.Fatalf("%d play items, want %d", got, want)
+	}
+	if got, want := connectionConditions(mpls)[2:4], []uint16{CCSeamless, CCNotSeamless}; !reflect.DeepEqual(got, want) {
+		t.Errorf("connection conditions at the join = %v, want %v", got, want)
+	}
+	if len(mpls.Playlist.SubPaths) != 1 || len(mpls.Playlist.SubPaths[0].SubPlayItems) != 6 {
+		t.Errorf("sub paths = %+v, want one with 6 sub play items", mpls.Playlist.SubPaths)
+	}
+	if got := mpls.MarkPlaylist.Marks[len(mpls.MarkPlaylist.Marks)-1].PlayItemRef; got != 5 {
+		t.Errorf("last mark refers to play item %d, want 5", got)
+	}
+	if other.Playlist.PlayItems[0].Flags&PIFConnectionCondition != CCNotSeamless || other.Playlist.PlayItems[1].Flags&PIFConnectionCondition != CCSeamless {
+		t.Error("other was modified")
+	}
+}
+
+func TestRemovePlayItemKeepsAsynchronousSubPaths(t *testing.T) {
+	mpls := editPlaylist()
+	menu := SubPath{
+		Type:         SPTInteractiveGraphics,
+		SubPlayItems: []SubPlayItem{{PlayItemID: 0, InTime: 0, OutTime: 90000, Clpi: CLPI{ClipFile: "00020", ClipID: "M2TS"}}},
+	}
+	mpls.Playlist.SubPaths = append(mpls.Playlist.SubPaths, menu)
+	mpls.update()
+
+	if err := mpls.RemovePlayItem(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := mpls.ReorderPlayItems([]int{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mpls.Playlist.SubPaths) != 2 || !reflect.DeepEqual(mpls.Playlist.SubPaths[1].SubPlayItems, menu.SubPlayItems) {
+		t.Errorf("sub paths = %+v, want the menu sub path unchanged", mpls.Playlist.SubPaths)
+	}
+}
+
+// spanningPlaylist returns editPlaylist with an out of mux SubPath whose only SubPlayItem is synchronized to PlayItem 0
+// and plays from 3000 to 5000 on the playlist timeline, running 1000 into PlayItem 1
+func spanningPlaylist() *MPLS {
+	mpls := editPlaylist()
+	mpls.Playlist.SubPaths = append(mpls.Playlist.SubPaths, SubPath{
+		Type: SPTOutOfMuxSynchronous,
+		SubPlayItems: []SubPlayItem{
+			{PlayItemID: 0, StartOfPlayitem: 4000, InTime: 0, OutTime: 2000, Clpi: CLPI{ClipFile: "00021", ClipID: "M2TS"}},
+		},
+	})
+	mpls.update()
+	return mpls
+}
+
+func TestTrimPlayItemSpanningSubPlayItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		index    int
+		in, out  Ticks
+		want     []SubPlayItem
+		timeline [][2]Ticks
+	}{
+		{
+			// the first 500 of PlayItem 1 are cut from the middle of the SubPlayItem
+			name:  "start of the next play item",
+			index: 1, in: 1500, out: 5000,
+			want: []SubPlayItem{
+				{PlayItemID: 0, StartOfPlayitem: 4000, InTime: 0, OutTime: 1000},
+				{PlayItemID: 1, StartOfPlayitem: 1500, InTime: 1500, OutTime: 2000},
+			},
+			timeline: [][2]Ticks{{3000, 4000}, {4000, 4500}},
+		},
+		{
+			// the last 500 of PlayItem 0 are cut, the rest of the SubPlayItem is synchronized to PlayItem 1
+			name:  "end of its own play item",
+			index: 0, in: 1000, out: 4500,
+			want: []SubPlayItem{
+				{PlayItemID: 0, StartOfPlayitem: 4000, InTime: 0, OutTime: 500},
+				{PlayItemID: 1, StartOfPlayitem: 1000, InTime: 1000, OutTime: 2000},
+			},
+			timeline: [][2]Ticks{{3000, 3500}, {3500, 4500}},
+		},
+		{
+			name:  "only the next play item",
+			index: 1, in: 2500, out: 5000,
+			want: []SubPlayItem{
+				{PlayItemID: 0, StartOfPlayitem: 4000, InTime: 0, OutTime: 1000},
+			},
+			timeline: [][2]Ticks{{3000, 4000}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mpls := spanningPlaylist()
+			if err := mpls.TrimPlayItem(test.index, test.in, test.out); err != nil {
+				t.Fatal(err)
+			}
+
+			sp := mpls.Playlist.SubPaths[1]
+			var got []SubPlayItem
+			for _, spi := range sp.SubPlayItems {
+				got = append(got, SubPlayItem{PlayItemID: spi.PlayItemID, StartOfPlayitem: spi.StartOfPlayitem, InTime: spi.InTime, OutTime: spi.OutTime})
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("sub play items = %+v, want %+v", got, test.want)
+			}
+
+			var timeline [][2]Ticks
+			for _, span := range mpls.Timeline(sp) {
+				timeline = append(timeline, [2]Ticks{span.Start, span.End})
+			}
+			if !reflect.DeepEqual(timeline, test.timeline) {
+				t.Errorf("timeline = %v, want %v", timeline, test.timeline)
+			}
+		})
+	}
+}
+
+// stereoscopicPlaylist returns editPlaylist with two extension SubPaths, one playing the start of PlayItem 2
+// and one covering every PlayItem, and a dependent view from the extension SubPath at dependentView
+func stereoscopicPlaylist(dependentView byte) *MPLS {
+	mpls := editPlaylist()
+	mpls.ExtensionData.SubPaths = []SubPath{
+		{Type: SPTStereoscopicVideo, SubPlayItems: []SubPlayItem{
+			{PlayItemID: 2, StartOfPlayitem: 1000, InTime: 0, OutTime: 1000, Clpi: CLPI{ClipFile: "00033", ClipID: "M2TS"}},
+		}},
+		{Type: SPTStereoscopicVideo},
+	}
+	for i, clip := range []string{"00041", "00042", "00043"} {
+		mpls.ExtensionData.SubPaths[1].SubPlayItems = append(mpls.ExtensionData.SubPaths[1].SubPlayItems, SubPlayItem{
+			PlayItemID: uint16(i), StartOfPlayitem: 1000, InTime: 1000, OutTime: 5000, Clpi: CLPI{ClipFile: clip, ClipID: "M2TS"},
+		})
+		mpls.Playlist.PlayItems[i].StreamTable.SS = STNTableSS{
+			DependentViewStreams: []DependentViewStream{{PrimaryStream: PrimaryStream{StreamEntry: StreamEntry{Type: 2, PID: 0x1012, SubPathID: dependentView}}}},
+		}
+	}
+	mpls.ExtensionData.Entries = []ExtensionEntry{{ID1: 1, ID2: 1}, {ID1: 2, ID2: 1}, {ID1: 2, ID2: 2}}
+	mpls.ExtensionData.order = []int{2, 0, 1}
+	mpls.update()
+	return mpls
+}
+
+func TestRemovePlayItemExtensionSubPaths(t *testing.T) {
+	// the dependent view comes from the SubPath that is kept and follows it to its new index
+	mpls := stereoscopicPlaylist(1)
+	if err := mpls.RemovePlayItem(2); err != nil {
+		t.Fatal(err)
+	}
+	if len(mpls.ExtensionData.SubPaths) != 1 || len(mpls.ExtensionData.SubPaths[0].SubPlayItems) != 2 {
+		t.Fatalf("extension sub paths = %+v, want the one covering every play item", mpls.ExtensionData.SubPaths)
+	}
+	for i, pi := range mpls.Playlist.PlayItems {
+		if dvs := pi.StreamTable.SS.DependentViewStreams; len(dvs) != 1 || dvs[0].SubPathID != 0 {
+			t.Errorf("play item %d dependent views = %+v, want SubPathID 0", i, dvs)
+		}
+	}
+	if len(mpls.ExtensionData.Entries) != 3 {
+		t.Errorf("extension entries = %+v, want all 3", mpls.ExtensionData.Entries)
+	}
+
+	// the dependent view comes from the removed SubPath, the STN_table_SS goes with it
+	mpls = stereoscopicPlaylist(0)
+	if err := mpls.TrimPlayItem(2, 3000, 5000); err != nil {
+		t.Fatal(err)
+	}
+	if len(mpls.ExtensionData.SubPaths) != 1 || len(mpls.ExtensionData.SubPaths[0].SubPlayItems) != 3 {
+		t.Fatalf("extension sub paths = %+v, want the one covering every play item", mpls.ExtensionData.SubPaths)
+	}
+	for i, pi := range mpls.Playlist.PlayItems {
+		if !reflect.DeepEqual(pi.StreamTable.SS, STNTableSS{}) {
+			t.Errorf("play item %d STN_table_SS = %+v, want none", i, pi.StreamTable.SS)
+		}
+	}
+	want := []ExtensionEntry{{ID1: 1, ID2: 1}, {ID1: 2, ID2: 2}}
+	if !reflect.DeepEqual(mpls.ExtensionData.Entries, want) || mpls.ExtensionData.EntryCount != 2 {
+		t.Errorf("extension entries = %+v, want %+v", mpls.ExtensionData.Entries, want)
+	}
+	if !reflect.DeepEqual(mpls.ExtensionData.order, []int{1, 0}) {
+		t.Errorf("extension data order = %v, want [1 0]", mpls.ExtensionData.order)
+	}
+}
+
+// streamsTable returns an STNTable with two streams of every primary kind, a PiP PG stream,
+// secondary streams referring to them and the STN_table_SS entries of the video and graphics streams
+func streamsTable() STNTable {
+	primary := func(pids ...uint16) []PrimaryStream {
+		var streams []PrimaryStream
+		for _, pid := range pids {
+			streams = append(streams, PrimaryStream{StreamEntry: StreamEntry{Type: 1, PID: pid}})
+		}
+		return streams
+	}
+	secondary := func(ids ...byte) SecondaryStream {
+		return SecondaryStream{RefrenceEntryCount: byte(len(ids)), StreamIDs: ids}
+	}
+	graphicsSS := func(ids ...byte) []GraphicsStreamSS {
+		var streams []GraphicsStreamSS
+		for _, id := range ids {
+			streams = append(streams, GraphicsStreamSS{OffsetSequenceID: id})
+		}
+		return streams
+	}
+
+	return STNTable{
+		PrimaryVideoStreams: primary(0x1011, 0x1012),
+		PrimaryAudioStreams: primary(0x1100, 0x1101),
+		PrimaryPGStreams:    primary(0x1200, 0x1201),
+		PIPPGStreams:        primary(0x1A00),
+		PrimaryIGStreams:    primary(0x1400, 0x1401),
+		SecondaryAudioStreams: []SecondaryAudioStream{
+			{PrimaryStream: primary(0x1A10)[0], ExtraAttributes: secondary(0)},
+			{PrimaryStream: primary(0x1A11)[0], ExtraAttributes: secondary(0, 1)},
+		},
+		SecondaryVideoStreams: []SecondaryVideoStream{
+			{PrimaryStream: primary(0x1B00)[0], ExtraAttributes: secondary(0, 1), PGStream: secondary(1, 2)},
+		},
+		SS: STNTableSS{
+			DependentViewStreams: []DependentViewStream{{PrimaryStream: primary(0x1012)[0]}, {PrimaryStream: primary(0x1013)[0]}},
+			PGStreams:            graphicsSS(0, 1, 2),
+			IGStreams:            graphicsSS(3, 4),
+		},
+	}
+}
+
+func TestSTNTableDropStreams(t *testing.T) {
+	stnt := streamsTable()
+	original := streamsTable()
+	dropped := map[uint16]bool{0x1011: true, 0x1100: true, 0x1200: true, 0x1400: true, 0x1A10: true}
+	result := stnt
+	result.DropStreams(func(kind StreamKind, stream PrimaryStream) bool {
+		return !dropped[stream.PID]
+	})
+
+	if len(result.PrimaryVideoStreams) != 1 || len(result.PrimaryAudioStreams) != 1 || len(result.PrimaryPGStreams) != 1 || len(result.PIPPGStreams) != 1 || len(result.PrimaryIGStreams) != 1 {
+		t.Fatalf("streams left = %+v", result)
+	}
+	if result.PrimaryVideoStreamCount != 1 || result.SecondaryAudioStreamCount != 1 {
+		t.Errorf("counts were not updated: %+v", result)
+	}
+
+	// the second secondary audio stream mixes with both primary audio streams, only the second is left at index 0
+	if sas := result.SecondaryAudioStreams; len(sas) != 1 || sas[0].PID != 0x1A11 || !reflect.DeepEqual(sas[0].ExtraAttributes.StreamIDs, []byte{0}) {
+		t.Errorf("secondary audio streams = %+v", sas)
+	}
+	// the secondary video stream refers to the secondary audio stream left and to the primary and PiP PG stream left
+	svs := result.SecondaryVideoStreams
+	if len(svs) != 1 || !reflect.DeepEqual(svs[0].ExtraAttributes, SecondaryStream{RefrenceEntryCount: 1, StreamIDs: []byte{0}}) || !reflect.DeepEqual(svs[0].PGStream, SecondaryStream{RefrenceEntryCount: 2, StreamIDs: []byte{0, 1}}) {
+		t.Errorf("secondary video streams = %+v", svs)
+	}
+
+	// every stereoscopic entry follows the stream at the same index
+	ss := result.SS
+	if len(ss.DependentViewStreams) != 1 || ss.DependentViewStreams[0].PID != 0x1013 {
+		t.Errorf("dependent views = %+v, want the one of the second video stream", ss.DependentViewStreams)
+	}
+	if want := []GraphicsStreamSS{{OffsetSequenceID: 1}, {OffsetSequenceID: 2}}; !reflect.DeepEqual(ss.PGStreams, want) {
+		t.Errorf("PG streams SS = %+v, want %+v", ss.PGStreams, want)
+	}
+	if want := []GraphicsStreamSS{{OffsetSequenceID: 4}}; !reflect.DeepEqual(ss.IGStreams, want) {
+		t.Errorf("IG streams SS = %+v, want %+v", ss.IGStreams, want)
+	}
+
+	if !reflect.DeepEqual(stnt, original) {
+		t.Error("the lists of the copied table were modified")
+	}
+}
+
+func TestConcatSubPathTypes(t *testing.T) {
+	mpls, other := editPlaylist(), editPlaylist()
+	other.Playlist.SubPaths[0].Type = SPTOutOfMuxSynchronous
+	if err := mpls.Concat(other); err != nil {
+		t.Fatal(err)
+	}
+
+	// the SubPaths of other are appended and its streams refer to them after the existing ones
+	if len(mpls.Playlist.SubPaths) != 2 || mpls.Playlist.SubPaths[1].Type != SPTOutOfMuxSynchronous {
+		t.Fatalf("sub paths = %+v, want the sub path of other appended", mpls.Playlist.SubPaths)
+	}
+	for _, spi := range mpls.Playlist.SubPaths[1].SubPlayItems {
+		if spi.PlayItemID < 3 {
+			t.Errorf("sub play item of other refers to play item %d", spi.PlayItemID)
+		}
+	}
+	for i, pi := range mpls.Playlist.PlayItems {
+		want := byte(0)
+		if i >= 3 {
+			want = 1
+		}
+		if got := pi.StreamTable.PrimaryPGStreams[0].SubPathID; got != want {
+			t.Errorf("play item %d PG stream SubPathID = %d, want %d", i, got, want)
+		}
+	}
+	if other.Playlist.PlayItems[0].StreamTable.PrimaryPGStreams[0].SubPathID != 0 {
+		t.Error("other was modified")
+	}
+
+	mpls, other = editPlaylist(), editPlaylist()
+	other.ExtensionData.SubPaths = []SubPath{{Type: SPTStereoscopicVideo}}
+	if err := mpls.Concat(other); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("different extension sub paths returned %v, want ErrInvalidValue", err)
+	}
+	if len(mpls.Playlist.PlayItems) != 3 {
+		t.Error("the playlist was modified by a failed Concat")
+	}
+}
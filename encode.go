@@ -0,0 +1,931 @@
+package mpls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type errWriter struct {
+	buf  []byte
+	err  error
+	path []string
+}
+
+func (ew *errWriter) Write(p []byte) (n int, err error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+
+	ew.buf = append(ew.buf, p...)
+	return len(p), nil
+}
+
+// enter pushes a section onto the path used to report errors
+func (ew *errWriter) enter(format string, a ...interface{}) {
+	ew.path = append(ew.path, fmt.Sprintf(format, a...))
+}
+
+// leave pops the last section entered
+func (ew *errWriter) leave() {
+	ew.path = ew.path[:len(ew.path)-1]
+}
+
+// fail records an ErrInvalidValue for the current section unless an error has already been recorded
+func (ew *errWriter) fail(format string, a ...interface{}) error {
+	if ew.err == nil {
+		ew.err = &EncodeError{
+			Section: strings.Join(ew.path, "."),
+			Err:     fmt.Errorf("%w: %s", ErrInvalidValue, fmt.Sprintf(format, a...)),
+		}
+	}
+	return ew.err
+}
+
+// pos returns the number of bytes written
+func (ew *errWriter) pos() int {
+	return len(ew.buf)
+}
+
+// begin writes a placeholder for a length field of size bytes and returns its position
+func (ew *errWriter) begin(size int) int {
+	pos := len(ew.buf)
+	_, _ = ew.Write(make([]byte, size))
+	return pos
+}
+
+// end fills in the length field at pos with the number of bytes written after it
+func (ew *errWriter) end(pos, size int) {
+	if ew.err != nil {
+		return
+	}
+
+	length := uint64(len(ew.buf) - pos - size)
+	if length >= 1<<(8*uint(size)) {
+		_ = ew.fail("length %d does not fit in %d bytes", length, size)
+		return
+	}
+	switch size {
+	case 1:
+		ew.buf[pos] = byte(length)
+	case 2:
+		binary.BigEndian.PutUint16(ew.buf[pos:], uint16(length))
+	case 4:
+		binary.BigEndian.PutUint32(ew.buf[pos:], uint32(length))
+	}
+}
+
+// putUInt32 overwrites the 4 bytes at pos
+func (ew *errWriter) putUInt32(pos int, n int) {
+	if ew.err != nil {
+		return
+	}
+
+	binary.BigEndian.PutUint32(ew.buf[pos:], uint32(n))
+}
+
+// count fails if count does not fit in a field of max
+func (ew *errWriter) count(count, max int, name string) int {
+	if count > max {
+		_ = ew.fail("%d %s do not fit in a count of at most %d", count, name, max)
+	}
+	return count
+}
+
+// str writes s which must be exactly size bytes
+func (ew *errWriter) str(s string, size int, name string) {
+	if len(s) != size {
+		_ = ew.fail("%s %q is not %d bytes", name, s, size)
+		return
+	}
+	_, _ = ew.Write([]byte(s))
+}
+
+// MarshalBinary encodes the MPLS as the bytes of an .mpls file.
+// PlaylistStart, PlaylistMarkStart, ExtensionDataStart, every Len and every count are recomputed
+// from the contents, the struct itself is not modified
+func (mpls *MPLS) MarshalBinary() ([]byte, error) {
+	writer := &errWriter{}
+
+	err := mpls.encode(writer)
+	if err != nil {
+		return nil, err
+	}
+	return writer.buf, nil
+}
+
+// UnmarshalBinary parses the bytes of an .mpls file into the MPLS
+func (mpls *MPLS) UnmarshalBinary(data []byte) error {
+	return mpls.Parse(data)
+}
+
+// Encode writes the MPLS as an .mpls file to w
+func (mpls *MPLS) Encode(w io.Writer) error {
+	file, err := mpls.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(file)
+	return err
+}
+
+// encode writes MPLS data to an *errWriter
+func (mpls *MPLS) encode(writer *errWriter) error {
+	var (
+		err       error
+		addresses int
+	)
+
+	fileType, version := mpls.FileType, mpls.Version
+	if fileType == "" {
+		fileType = "MPLS"
+	}
+	if version == "" {
+		version = "0200"
+	}
+	writer.str(fileType, 4, "file type")
+	writer.str(version, 4, "version")
+
+	addresses = writer.begin(12)
+	_, _ = writer.Write(mpls.reserved[:])
+
+	writer.enter("AppInfoPlaylist")
+	err = mpls.AppInfoPlaylist.encode(writer)
+	writer.leave()
+	if err != nil {
+		return err
+	}
+
+	_, _ = writer.Write(mpls.padding[0])
+	writer.putUInt32(addresses, writer.pos())
+
+	writer.enter("Playlist")
+	err = mpls.Playlist.encode(writer)
+	writer.leave()
+	if err != nil {
+		return err
+	}
+
+	_, _ = writer.Write(mpls.padding[1])
+	writer.putUInt32(addresses+4, writer.pos())
+
+	writer.enter("MarkPlaylist")
+	err = mpls.MarkPlaylist.encode(writer)
+	writer.leave()
+	if err != nil {
+		return err
+	}
+
+	if mpls.ExtensionDataStart != 0 || len(mpls.ExtensionData.Entries) > 0 {
+		_, _ = writer.Write(mpls.padding[2])
+		writer.putUInt32(addresses+8, writer.pos())
+
+		writer.enter("ExtensionData")
+		err = mpls.ExtensionData.encode(writer, mpls.Playlist.PlayItems)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _ = writer.Write(mpls.padding[3])
+
+	return writer.err
+}
+
+// encode writes AppInfoPlaylist data to an *errWriter
+func (aip *AppInfoPlaylist) encode(writer *errWriter) error {
+	start := writer.begin(4)
+
+	_, _ = writer.Write([]byte{aip.reserved, aip.PlaybackType})
+	writeUInt16(writer, aip.PlaybackCount)
+	writeUInt64(writer, aip.UOMask)
+	writeUInt16(writer, aip.PlaylistFlags)
+	_, _ = writer.Write(aip.extra)
+
+	writer.end(start, 4)
+	return writer.err
+}
+
+// encode writes Playlist data to an *errWriter
+func (p *Playlist) encode(writer *errWriter) error {
+	var (
+		err error
+	)
+
+	start := writer.begin(4)
+
+	_, _ = writer.Write(p.reserved[:])
+	writeUInt16(writer, uint16(writer.count(len(p.PlayItems), 0xFFFF, "PlayItems")))
+	writeUInt16(writer, uint16(writer.count(len(p.SubPaths), 0xFFFF, "SubPaths")))
+
+	for i := range p.PlayItems {
+		writer.enter("PlayItems[%d]", i)
+		err = p.PlayItems[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range p.SubPaths {
+		writer.enter("SubPaths[%d]", i)
+		err = p.SubPaths[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _ = writer.Write(p.extra)
+
+	writer.end(start, 4)
+	return writer.err
+}
+
+// encode writes PlayItem data to an *errWriter
+func (pi *PlayItem) encode(writer *errWriter) error {
+	var (
+		err error
+	)
+
+	start := writer.begin(2)
+
+	pi.Clpi.encode(writer)
+	writeUInt16(writer, pi.Flags)
+	_, _ = writer.Write([]byte{pi.Clpi.STCID})
+	writeUInt32(writer, uint32(pi.InTime))
+	writeUInt32(writer, uint32(pi.OutTime))
+	writeUInt64(writer, pi.UOMask)
+	_, _ = writer.Write([]byte{pi.RandomAccessFlag, pi.StillMode})
+	writeUInt16(writer, pi.StillTime)
+
+	if pi.Flags&PIFIsMultiAngle != 0 {
+		count := writer.count(len(pi.Angles)+1, 0xFF, "angles")
+		_, _ = writer.Write([]byte{byte(count), pi.AngleFlags})
+		for i := range pi.Angles {
+			writer.enter("Angles[%d]", i)
+			pi.Angles[i].encode(writer)
+			writer.leave()
+			_, _ = writer.Write([]byte{pi.Angles[i].STCID})
+		}
+	}
+
+	writer.enter("StreamTable")
+	err = pi.StreamTable.encode(writer)
+	writer.leave()
+	if err != nil {
+		return err
+	}
+
+	_, _ = writer.Write(pi.extra)
+
+	writer.end(start, 2)
+	return writer.err
+}
+
+// encode writes the clip name and codec identifier to an *errWriter
+func (clpi *CLPI) encode(writer *errWriter) {
+	writer.str(clpi.ClipFile, 5, "clip file")
+	writer.str(clpi.ClipID, 4, "clip id")
+}
+
+// encode writes STNTable data to an *errWriter
+func (stnt *STNTable) encode(writer *errWriter) error {
+	var (
+		err error
+	)
+
+	start := writer.begin(2)
+
+	_, _ = writer.Write(stnt.reserved[:2])
+	_, _ = writer.Write([]byte{
+		byte(writer.count(len(stnt.PrimaryVideoStreams), 0xFF, "PrimaryVideoStreams")),
+		byte(writer.count(len(stnt.PrimaryAudioStreams), 0xFF, "PrimaryAudioStreams")),
+		byte(writer.count(len(stnt.PrimaryPGStreams), 0xFF, "PrimaryPGStreams")),
+		byte(writer.count(len(stnt.PrimaryIGStreams), 0xFF, "PrimaryIGStreams")),
+		byte(writer.count(len(stnt.SecondaryAudioStreams), 0xFF, "SecondaryAudioStreams")),
+		byte(writer.count(len(stnt.SecondaryVideoStreams), 0xFF, "SecondaryVideoStreams")),
+		byte(writer.count(len(stnt.PIPPGStreams), 0xFF, "PIPPGStreams")),
+	})
+	if len(stnt.DolbyVisionStreams) > 0 {
+		_, _ = writer.Write([]byte{byte(writer.count(len(stnt.DolbyVisionStreams), 0xFF, "DolbyVisionStreams"))})
+		_, _ = writer.Write(stnt.reserved[3:])
+	} else {
+		_, _ = writer.Write(stnt.reserved[2:])
+	}
+
+	for i := range stnt.PrimaryVideoStreams {
+		writer.enter("PrimaryVideoStreams[%d]", i)
+		err = stnt.PrimaryVideoStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stnt.PrimaryAudioStreams {
+		writer.enter("PrimaryAudioStreams[%d]", i)
+		err = stnt.PrimaryAudioStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stnt.PrimaryPGStreams {
+		writer.enter("PrimaryPGStreams[%d]", i)
+		err = stnt.PrimaryPGStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stnt.PIPPGStreams {
+		writer.enter("PIPPGStreams[%d]", i)
+		err = stnt.PIPPGStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stnt.PrimaryIGStreams {
+		writer.enter("PrimaryIGStreams[%d]", i)
+		err = stnt.PrimaryIGStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stnt.SecondaryAudioStreams {
+		writer.enter("SecondaryAudioStreams[%d]", i)
+		err = stnt.SecondaryAudioStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stnt.SecondaryVideoStreams {
+		writer.enter("SecondaryVideoStreams[%d]", i)
+		err = stnt.SecondaryVideoStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stnt.DolbyVisionStreams {
+		writer.enter("DolbyVisionStreams[%d]", i)
+		err = stnt.DolbyVisionStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _ = writer.Write(stnt.Extra)
+
+	writer.end(start, 2)
+	return writer.err
+}
+
+// encode writes SecondaryStream data to an *errWriter
+func (ss *SecondaryStream) encode(writer *errWriter) error {
+	count := writer.count(len(ss.StreamIDs), 0xFF, "stream references")
+	_, _ = writer.Write([]byte{byte(count), ss.reserved[0]})
+	_, _ = writer.Write(ss.StreamIDs)
+	if count%2 != 0 {
+		_, _ = writer.Write(ss.reserved[1:])
+	}
+	return writer.err
+}
+
+// encode writes SecondaryAudioStream data to an *errWriter
+func (sas *SecondaryAudioStream) encode(writer *errWriter) error {
+	_ = sas.PrimaryStream.encode(writer)
+	writer.enter("ExtraAttributes")
+	_ = sas.ExtraAttributes.encode(writer)
+	writer.leave()
+
+	return writer.err
+}
+
+// encode writes SecondaryVideoStream data to an *errWriter
+func (svs *SecondaryVideoStream) encode(writer *errWriter) error {
+	_ = svs.PrimaryStream.encode(writer)
+	writer.enter("ExtraAttributes")
+	_ = svs.ExtraAttributes.encode(writer)
+	writer.leave()
+	writer.enter("PGStream")
+	_ = svs.PGStream.encode(writer)
+	writer.leave()
+
+	return writer.err
+}
+
+// encode writes STNTableSS data to an *errWriter
+func (ss *STNTableSS) encode(writer *errWriter) error {
+	var (
+		err error
+	)
+
+	start := writer.begin(2)
+
+	flags := ss.flags &^ 0x8000
+	if ss.FixedOffsetDuringPopUp {
+		flags |= 0x8000
+	}
+	writeUInt16(writer, flags)
+
+	for i := range ss.DependentViewStreams {
+		writer.enter("DependentViewStreams[%d]", i)
+		err = ss.DependentViewStreams[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range ss.PGStreams {
+		writer.enter("PGStreams[%d]", i)
+		err = ss.PGStreams[i].encode(writer, true)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range ss.IGStreams {
+		writer.enter("IGStreams[%d]", i)
+		err = ss.IGStreams[i].encode(writer, false)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _ = writer.Write(ss.Extra)
+
+	writer.end(start, 2)
+	return writer.err
+}
+
+// encode writes DependentViewStream data to an *errWriter
+func (dvs *DependentViewStream) encode(writer *errWriter) error {
+	_ = dvs.PrimaryStream.encode(writer)
+	writeUInt16(writer, dvs.flags&^0x3F|uint16(dvs.OffsetSequenceCount&0x3F))
+
+	return writer.err
+}
+
+// encode writes GraphicsStreamSS data to an *errWriter, pg selects the PG layout over the IG layout
+func (gs *GraphicsStreamSS) encode(writer *errWriter, pg bool) error {
+	flags := gs.flags
+	if pg {
+		flags &^= 0x0F
+		if gs.DialogRegionOffsetValid {
+			flags |= 0x08
+		}
+		if gs.IsSS {
+			flags |= 0x04
+		}
+		if gs.IsTopAS {
+			flags |= 0x02
+		}
+		if gs.IsBottomAS {
+			flags |= 0x01
+		}
+	} else {
+		flags &^= 0x01
+		if gs.IsSS {
+			flags |= 0x01
+		}
+	}
+	_, _ = writer.Write([]byte{gs.OffsetSequenceID, flags})
+
+	if gs.IsSS {
+		writer.enter("Left")
+		gs.Left.encode(writer)
+		writer.leave()
+		writer.enter("Right")
+		gs.Right.encode(writer)
+		writer.leave()
+		_, _ = writer.Write([]byte{gs.reserved[0], gs.SSOffsetSequenceID})
+	}
+
+	if pg && gs.IsTopAS {
+		writer.enter("Top")
+		gs.Top.encode(writer)
+		writer.leave()
+		_, _ = writer.Write([]byte{gs.reserved[1], gs.TopOffsetSequenceID})
+	}
+
+	if pg && gs.IsBottomAS {
+		writer.enter("Bottom")
+		gs.Bottom.encode(writer)
+		writer.leave()
+		_, _ = writer.Write([]byte{gs.reserved[2], gs.BottomOffsetSequenceID})
+	}
+
+	return writer.err
+}
+
+// encode writes Stream data to an *errWriter
+func (ps *PrimaryStream) encode(writer *errWriter) error {
+	writer.enter("StreamEntry")
+	ps.StreamEntry.encode(writer)
+	writer.leave()
+	writer.enter("StreamAttributes")
+	ps.StreamAttributes.encode(writer)
+	writer.leave()
+
+	return writer.err
+}
+
+// overlay returns a copy of raw that is at least size bytes long, new streams default to length
+func overlay(raw []byte, size, length int) []byte {
+	if len(raw) > 0 {
+		length = len(raw)
+	}
+	if length < size {
+		length = size
+	}
+	buf := make([]byte, length)
+	copy(buf, raw)
+	return buf
+}
+
+// encode writes Stream data to an *errWriter
+func (se *StreamEntry) encode(writer *errWriter) {
+	buf := overlay(se.raw, 5, 9)
+
+	buf[0] = se.Type
+	switch se.Type {
+	case 1:
+		binary.BigEndian.PutUint16(buf[1:3], se.PID)
+	case 2, 4:
+		buf[1] = se.SubPathID
+		buf[2] = se.SubClipID
+		binary.BigEndian.PutUint16(buf[3:5], se.PID)
+	case 3:
+		buf[1] = se.SubPathID
+		binary.BigEndian.PutUint16(buf[2:4], se.PID)
+	}
+
+	start := writer.begin(1)
+	_, _ = writer.Write(buf)
+	writer.end(start, 1)
+}
+
+// encode writes Stream data to an *errWriter
+func (sa *StreamAttributes) encode(writer *errWriter) {
+	buf := overlay(sa.raw, 5, 5)
+
+	buf[0] = byte(sa.Encoding)
+	switch sa.Encoding {
+	case VTMPEG1Video, VTMPEG2Video, VTVC1, VTH264, VTMVC:
+		buf[1] = sa.Format<<4 | sa.Rate&0x0F
+
+	case VTHEVC:
+		buf[1] = sa.Format<<4 | sa.Rate&0x0F
+		buf[2] = byte(sa.DynamicRange)<<4 | byte(sa.ColorSpace)&0x0F
+		buf[3] &^= 0xC0
+		if sa.CRFlag {
+			buf[3] |= 0x80
+		}
+		if sa.HDRPlusFlag {
+			buf[3] |= 0x40
+		}
+
+	case ATMPEG1Audio, ATMPEG2Audio, ATLPCM, ATAC3, ATDTS, ATTRUEHD, ATAC3Plus, ATDTSHD, ATDTSHDMaster, ATAC3PlusSecondary, ATDTSHDSecondary:
+		buf[1] = sa.Format<<4 | sa.Rate&0x0F
+		sa.language(writer, buf[2:5])
+
+	case PresentationGraphics, InteractiveGraphics:
+		sa.language(writer, buf[1:4])
+
+	case TextSubtitle:
+		buf[1] = byte(sa.CharacterCode)
+		sa.language(writer, buf[2:5])
+	}
+
+	start := writer.begin(1)
+	_, _ = writer.Write(buf)
+	writer.end(start, 1)
+}
+
+// language copies the 3 byte language code into buf
+func (sa *StreamAttributes) language(writer *errWriter, buf []byte) {
+	if len(sa.Language) != 3 {
+		_ = writer.fail("language %q is not 3 bytes", sa.Language)
+		return
+	}
+	copy(buf, sa.Language)
+}
+
+// encode writes PlaylistMark data to an *errWriter
+func (plm *PlaylistMark) encode(writer *errWriter) error {
+	start := writer.begin(4)
+
+	writeUInt16(writer, uint16(writer.count(len(plm.Marks), 0xFFFF, "Marks")))
+	for _, mark := range plm.Marks {
+		_, _ = writer.Write([]byte{mark.reserved, mark.Type})
+		writeUInt16(writer, mark.PlayItemRef)
+		writeUInt32(writer, uint32(mark.Time))
+		writeUInt16(writer, mark.PID)
+		writeUInt32(writer, uint32(mark.Duration))
+	}
+	_, _ = writer.Write(plm.extra)
+
+	writer.end(start, 4)
+	return writer.err
+}
+
+// encode writes ExtensionData data to an *errWriter
+func (ed *ExtensionData) encode(writer *errWriter, playitems []PlayItem) error {
+	var (
+		err   error
+		table int
+	)
+
+	start := writer.begin(4)
+	if len(ed.Entries) == 0 && ed.Len == 0 {
+		return writer.err
+	}
+
+	dataBlock := writer.begin(4)
+	_, _ = writer.Write(ed.reserved[:])
+	_, _ = writer.Write([]byte{byte(writer.count(len(ed.Entries), 0xFF, "Entries"))})
+
+	table = writer.pos()
+	for _, entry := range ed.Entries {
+		writeUInt16(writer, entry.ID1)
+		writeUInt16(writer, entry.ID2)
+		writeUInt32(writer, uint32(entry.Start))
+		writeUInt32(writer, 0)
+	}
+
+	_, _ = writer.Write(ed.padding)
+	writer.putUInt32(dataBlock, writer.pos()-start)
+
+	for _, i := range ed.dataOrder() {
+		entry := ed.Entries[i]
+		if len(entry.Data) == 0 && (!entry.encoded() || ed.empty(entry, playitems)) {
+			continue
+		}
+
+		_, _ = writer.Write(entry.padding)
+		entryStart := writer.pos()
+
+		writer.enter("Entries[%d]", i)
+		err = ed.encodeEntry(writer, entry, playitems)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+
+		writer.putUInt32(table+i*12+4, entryStart-start)
+		writer.putUInt32(table+i*12+8, writer.pos()-entryStart)
+	}
+
+	_, _ = writer.Write(ed.extra)
+
+	writer.end(start, 4)
+	return writer.err
+}
+
+// dataOrder returns the indexes of the entries in the order their data is written,
+// the order they were parsed in followed by entries added since
+func (ed *ExtensionData) dataOrder() []int {
+	var (
+		order = make([]int, 0, len(ed.Entries))
+		seen  = make([]bool, len(ed.Entries))
+	)
+
+	for _, i := range ed.order {
+		if i < len(ed.Entries) && !seen[i] {
+			order = append(order, i)
+			seen[i] = true
+		}
+	}
+	for i := range ed.Entries {
+		if !seen[i] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// empty reports whether an encoded entry has nothing to encode,
+// an entry that was empty when parsed is then left empty
+func (ed *ExtensionData) empty(entry ExtensionEntry, playitems []PlayItem) bool {
+	switch entry.ID() {
+	case ExtensionSubPaths:
+		return len(ed.SubPaths) == 0
+	case ExtensionSTNTableSS:
+		for _, playitem := range playitems {
+			ss := playitem.StreamTable.SS
+			if ss.FixedOffsetDuringPopUp || len(ss.DependentViewStreams)+len(ss.PGStreams)+len(ss.IGStreams)+len(ss.Extra) > 0 {
+				return false
+			}
+		}
+		return true
+	case ExtensionPiPMetadata:
+		return len(ed.PiPMetadata) == 0
+	}
+	return false
+}
+
+// encoded reports whether the entry is encoded from decoded fields rather than from Data
+func (ee ExtensionEntry) encoded() bool {
+	switch ee.ID() {
+	case ExtensionSubPaths, ExtensionSTNTableSS, ExtensionPiPMetadata:
+		return true
+	}
+	return false
+}
+
+// encodeEntry writes the payload of an extension entry to an *errWriter
+func (ed *ExtensionData) encodeEntry(writer *errWriter, entry ExtensionEntry, playitems []PlayItem) error {
+	var (
+		err error
+	)
+
+	switch entry.ID() {
+	case ExtensionSubPaths:
+		start := writer.begin(4)
+		writeUInt16(writer, uint16(writer.count(len(ed.SubPaths), 0xFFFF, "SubPaths")))
+		for i := range ed.SubPaths {
+			writer.enter("SubPaths[%d]", i)
+			err = ed.SubPaths[i].encode(writer)
+			writer.leave()
+			if err != nil {
+				return err
+			}
+		}
+		writer.end(start, 4)
+		_, _ = writer.Write(entry.extra)
+
+	case ExtensionSTNTableSS:
+		for i := range playitems {
+			writer.enter("STNTableSS[%d]", i)
+			err = playitems[i].StreamTable.SS.encode(writer)
+			writer.leave()
+			if err != nil {
+				return err
+			}
+		}
+		_, _ = writer.Write(entry.extra)
+
+	case ExtensionPiPMetadata:
+		start := writer.begin(4)
+		writeUInt16(writer, uint16(writer.count(len(ed.PiPMetadata), 0xFFFF, "PiPMetadata")))
+		headers := writer.pos()
+		for i := range ed.PiPMetadata {
+			writer.enter("PiPMetadata[%d]", i)
+			err = ed.PiPMetadata[i].encode(writer)
+			writer.leave()
+			if err != nil {
+				return err
+			}
+		}
+		for i := range ed.PiPMetadata {
+			writer.enter("PiPMetadata[%d]", i)
+			_, _ = writer.Write(ed.PiPMetadata[i].padding)
+			writer.putUInt32(headers+i*14+10, writer.pos()-start)
+			err = ed.PiPMetadata[i].encodeEntries(writer)
+			writer.leave()
+			if err != nil {
+				return err
+			}
+		}
+		writer.end(start, 4)
+		_, _ = writer.Write(entry.extra)
+
+	default:
+		_, _ = writer.Write(entry.Data)
+	}
+
+	return writer.err
+}
+
+// encode writes the PiPMetadata block header to an *errWriter, the data address is filled in by the caller
+func (pm *PiPMetadata) encode(writer *errWriter) error {
+	if pm.TimelineType > 0x0F {
+		return writer.fail("PiP timeline type %d does not fit in 4 bits", pm.TimelineType)
+	}
+
+	writeUInt16(writer, pm.PlayItemRef)
+	_, _ = writer.Write([]byte{pm.SecondaryVideoRef, pm.reserved[0]})
+
+	flags := uint16(pm.TimelineType)<<12 | pm.flags&0x03FF
+	if pm.LumaKey {
+		flags |= 0x0800
+	}
+	if pm.TrickPlay {
+		flags |= 0x0400
+	}
+	writeUInt16(writer, flags)
+
+	_, _ = writer.Write([]byte{pm.reserved[1], pm.UpperLimitLumaKey, pm.reserved[2], pm.reserved[3]})
+	writeUInt32(writer, 0)
+	return writer.err
+}
+
+// encodeEntries writes the PiP entries to an *errWriter
+func (pm *PiPMetadata) encodeEntries(writer *errWriter) error {
+	writeUInt16(writer, uint16(writer.count(len(pm.Entries), 0xFFFF, "Entries")))
+	for _, entry := range pm.Entries {
+		if entry.X > 0x0FFF || entry.Y > 0x0FFF || entry.Scale > 0x0F {
+			return writer.fail("PiP window at %d,%d scaled %s does not fit in 12 bit coordinates and a 4 bit scale", entry.X, entry.Y, entry.Scale)
+		}
+		writeUInt32(writer, uint32(entry.Time))
+		_, _ = writer.Write([]byte{
+			byte(entry.X >> 4),
+			byte(entry.X<<4) | byte(entry.Y>>8),
+			byte(entry.Y),
+			byte(entry.Scale)<<4 | entry.reserved&0x0F,
+		})
+	}
+	return writer.err
+}
+
+// encode writes SubPath data to an *errWriter
+func (sp *SubPath) encode(writer *errWriter) error {
+	var (
+		err error
+	)
+
+	start := writer.begin(4)
+
+	_, _ = writer.Write([]byte{sp.reserved[0], byte(sp.Type)})
+	writeUInt16(writer, sp.Flags)
+	_, _ = writer.Write([]byte{sp.reserved[1], byte(writer.count(len(sp.SubPlayItems), 0xFF, "SubPlayItems"))})
+
+	for i := range sp.SubPlayItems {
+		writer.enter("SubPlayItems[%d]", i)
+		err = sp.SubPlayItems[i].encode(writer)
+		writer.leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _ = writer.Write(sp.extra)
+
+	writer.end(start, 4)
+	return writer.err
+}
+
+// encode writes SubPlayItem data to an *errWriter
+func (spi *SubPlayItem) encode(writer *errWriter) error {
+	start := writer.begin(2)
+
+	spi.Clpi.encode(writer)
+	_, _ = writer.Write(spi.reserved[:])
+	_, _ = writer.Write([]byte{spi.Flags, spi.Clpi.STCID})
+	writeUInt32(writer, uint32(spi.InTime))
+	writeUInt32(writer, uint32(spi.OutTime))
+	writeUInt16(writer, spi.PlayItemID)
+	writeUInt32(writer, uint32(spi.StartOfPlayitem))
+
+	if spi.Flags&SPIFIsMultiClipEntries != 0 {
+		count := writer.count(len(spi.Angles)+1, 0xFF, "clips")
+		_, _ = writer.Write([]byte{byte(count), spi.AngleFlags})
+		for i := range spi.Angles {
+			writer.enter("Angles[%d]", i)
+			spi.Angles[i].encode(writer)
+			writer.leave()
+			_, _ = writer.Write([]byte{spi.Angles[i].STCID})
+		}
+	}
+
+	_, _ = writer.Write(spi.extra)
+
+	writer.end(start, 2)
+	return writer.err
+}
+
+func writeUInt16(writer io.Writer, n uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], n)
+	_, _ = writer.Write(buf[:])
+}
+
+func writeUInt32(writer io.Writer, n uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], n)
+	_, _ = writer.Write(buf[:])
+}
+
+func writeUInt64(writer io.Writer, n uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	_, _ = writer.Write(buf[:])
+}
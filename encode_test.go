@@ -0,0 +1,357 @@
+package mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// The builders below lay out playlist files byte by byte, independently of the encoder,
+// so a round trip checks the encoder against the file format rather than against itself
+
+func u8(v byte) []byte { return []byte{v} }
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func cat(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
+
+// withLen16 and withLen32 prefix body with its length
+func withLen16(body []byte) []byte { return cat(u16(uint16(len(body))), body) }
+
+func withLen32(body []byte) []byte { return cat(u32(uint32(len(body))), body) }
+
+func testEntry(typ byte, pid uint16) []byte {
+	switch typ {
+	case 2:
+		return withLen8(cat(u8(2), u8(0), u8(0), u16(pid), make([]byte, 4)))
+	case 3:
+		return withLen8(cat(u8(3), u8(0), u16(pid), make([]byte, 5)))
+	}
+	return withLen8(cat(u8(1), u16(pid), make([]byte, 6)))
+}
+
+func withLen8(body []byte) []byte { return cat(u8(byte(len(body))), body) }
+
+func videoAttributes() []byte { return withLen8(cat(u8(byte(VTH264)), u8(0x61), []byte{7, 8, 9})) }
+
+func audioAttributes(language string) []byte {
+	return withLen8(cat(u8(byte(ATAC3)), u8(0x31), []byte(language)))
+}
+
+func pgAttributes(language string) []byte {
+	return withLen8(cat(u8(byte(PresentationGraphics)), []byte(language), u8(0)))
+}
+
+// testSTN is an STN table with a video, audio and PG stream, a secondary video stream if pip is set
+// and a Dolby Vision stream if dv is set. reserved fills the reserved bytes, extra follows the streams
+type testSTN struct {
+	pip, dv  bool
+	reserved byte
+	extra    []byte
+}
+
+func (ts testSTN) bytes() []byte {
+	var secondaryVideo byte
+	streams := cat(
+		testEntry(1, 0x1011), videoAttributes(),
+		testEntry(1, 0x1100), audioAttributes("eng"),
+		testEntry(1, 0x1200), pgAttributes("eng"),
+	)
+	if ts.pip {
+		secondaryVideo = 1
+		streams = cat(streams, testEntry(2, 0x1B00), videoAttributes(), u8(0), u8(0), u8(0), u8(0))
+	}
+	trailing := []byte{ts.reserved, ts.reserved, ts.reserved, ts.reserved, ts.reserved}
+	if ts.dv {
+		trailing[0] = 1
+		streams = cat(streams, testEntry(1, DolbyVisionELPID), videoAttributes())
+	}
+	return withLen16(cat(
+		[]byte{ts.reserved, ts.reserved},
+		[]byte{1, 1, 1, 0, 0, secondaryVideo, 0},
+		trailing,
+		streams,
+		ts.extra,
+	))
+}
+
+func testPlayItem(clip string, in, out uint32, angles []string, stn []byte) []byte {
+	var (
+		flags      = uint16(CCSeamless)
+		angleBytes []byte
+	)
+	if len(angles) > 0 {
+		flags |= PIFIsMultiAngle
+		angleBytes = cat(u8(byte(len(angles)+1)), u8(AFIsDifferentAudios))
+		for _, angle := range angles {
+			angleBytes = cat(angleBytes, []byte(angle+"M2TS"), u8(0))
+		}
+	}
+	return withLen16(cat(
+		[]byte(clip+"M2TS"), u16(flags), u8(0), u32(in), u32(out),
+		make([]byte, 8), u8(0x80), u8(0), u16(0),
+		angleBytes,
+		stn,
+	))
+}
+
+func testSubPlayItem(clip string, playitem uint16, start uint32) []byte {
+	return withLen16(cat([]byte(clip+"M2TS"), make([]byte, 3), u8(CCSeamlessNoOverlap<<1), u8(0), u32(0), u32(45000), u16(playitem), u32(start)))
+}
+
+func testSubPath(typ SubPathType, items ...[]byte) []byte {
+	return withLen32(cat(u8(0), u8(byte(typ)), u16(0), u8(0), u8(byte(len(items))), cat(items...)))
+}
+
+// testExtEntry is an extension entry, padding comes before its data.
+// Entries without data are written with a start and length of 0
+type testExtEntry struct {
+	id1, id2 uint16
+	data     []byte
+	padding  []byte
+}
+
+// testExtensionData lays out entries with their data in order, padding goes between the table and the data
+// and extra after the data
+func testExtensionData(entries []testExtEntry, order []int, padding, extra []byte) []byte {
+	var (
+		table = 12 + 12*len(entries)
+		data  []byte
+		start = make([]int, len(entries))
+	)
+
+	for _, i := range order {
+		if len(entries[i].data) == 0 {
+			continue
+		}
+		data = cat(data, entries[i].padding)
+		start[i] = table + len(padding) + len(data)
+		data = cat(data, entries[i].data)
+	}
+
+	body := cat(u32(uint32(table+len(padding))), make([]byte, 3), u8(byte(len(entries))))
+	for i, entry := range entries {
+		body = cat(body, u16(entry.id1), u16(entry.id2), u32(uint32(start[i])), u32(uint32(len(entry.data))))
+	}
+	return withLen32(cat(body, padding, data, extra))
+}
+
+// testFile lays out a playlist, padding[i] goes after section i
+type testFile struct {
+	version   string
+	playitems [][]byte
+	subpaths  [][]byte
+	extension []byte
+	padding   [4][]byte
+	reserved  byte
+}
+
+func (tf testFile) bytes() []byte {
+	appInfo := withLen32(cat(u8(0), u8(1), u16(0), make([]byte, 8), u16(0x4000)))
+	playlist := withLen32(cat(u16(0), u16(uint16(len(tf.playitems))), u16(uint16(len(tf.subpaths))), cat(tf.playitems...), cat(tf.subpaths...)))
+	marks := withLen32(cat(u16(3),
+		u8(0), u8(MarkEntry), u16(0), u32(90000), u16(0xFFFF), u32(0),
+		u8(0), u8(MarkEntry), u16(0), u32(180000), u16(0xFFFF), u32(45000),
+		u8(0), u8(MarkEntry), u16(1), u32(45000), u16(0xFFFF), u32(0),
+	))
+
+	playlistStart := headerLen + len(appInfo) + len(tf.padding[0])
+	marksStart := playlistStart + len(playlist) + len(tf.padding[1])
+	extensionStart := 0
+	if tf.extension != nil {
+		extensionStart = marksStart + len(marks) + len(tf.padding[2])
+	}
+
+	reserved := make([]byte, 20)
+	for i := range reserved {
+		reserved[i] = tf.reserved
+	}
+	return cat(
+		[]byte("MPLS"+tf.version), u32(uint32(playlistStart)), u32(uint32(marksStart)), u32(uint32(extensionStart)), reserved,
+		appInfo, tf.padding[0],
+		playlist, tf.padding[1],
+		marks, tf.padding[2],
+		tf.extension, tf.padding[3],
+	)
+}
+
+// testSTNSS is the STN_table_SS of a PlayItem with the streams of testSTN,
+// a dependent view with offset sequences and a stereoscopic PG stream
+func testSTNSS() []byte {
+	return withLen16(cat(
+		u16(0x8000),
+		testEntry(2, 0x1012), videoAttributes(), u16(0xC003),
+		u8(1), u8(0x04), testEntry(1, 0x1220), testEntry(1, 0x1240), u8(0), u8(2),
+	))
+}
+
+// testPiP is PiP metadata for the first secondary video stream of two PlayItems with padding between the blocks
+func testPiP() []byte {
+	var (
+		block = func(x uint16) []byte {
+			return cat(u16(2),
+				u32(1000), []byte{byte(x >> 4), byte(x<<4) | 0x01, 0x02, byte(PiPScaleHalf)<<4 | 0x05},
+				u32(3000), []byte{byte(x >> 4), byte(x << 4), 0x40, byte(PiPScaleQuarter) << 4},
+			)
+		}
+		header = func(playitem uint16, flags uint16, address int) []byte {
+			return cat(u16(playitem), u8(0), u8(0x11), u16(flags), u8(0), u8(16), u8(0), u8(0), u32(uint32(address)))
+		}
+		headers = 4 + 2 + 2*14
+		first   = block(100)
+		second  = block(1820)
+		padding = []byte{0xEE, 0xEE, 0xEE}
+	)
+	return withLen32(cat(
+		u16(2),
+		header(0, uint16(PiPTimelineSynchronous)<<12|0x0800, headers),
+		header(1, uint16(PiPTimelineSynchronous)<<12|0x0400|0x0155, headers+len(first)+len(padding)),
+		first, padding, second,
+	))
+}
+
+func testStaticMetadata() []byte {
+	metadata := cat(u8(0x10), make([]byte, 3))
+	for _, v := range []uint16{34000, 16000, 13250, 34500, 7500, 3000, 15635, 16450, 1000, 50, 1000, 400} {
+		metadata = cat(metadata, u16(v))
+	}
+	return withLen32(cat(u8(1), make([]byte, 3), metadata))
+}
+
+// roundTrip checks that file parses with the given number of warnings and encodes back to the same bytes
+func roundTrip(t *testing.T, file []byte, warnings int) *MPLS {
+	t.Helper()
+	var mpls MPLS
+	if err := mpls.Parse(file); err != nil {
+		t.Fatal(err)
+	}
+	if len(mpls.Warnings) != warnings {
+		t.Errorf("warnings: %v, want %d", mpls.Warnings, warnings)
+	}
+	encoded, err := mpls.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, file) {
+		for i := range encoded {
+			if i >= len(file) || encoded[i] != file[i] {
+				t.Fatalf("encoded %d bytes, want %d, first difference at offset %d", len(encoded), len(file), i)
+			}
+		}
+		t.Fatalf("encoded %d bytes, want %d", len(encoded), len(file))
+	}
+	return &mpls
+}
+
+func TestRoundTrip(t *testing.T) {
+	stn := testSTN{reserved: 0x5A, extra: []byte{0xAB, 0xCD}}.bytes()
+	// the extra bytes of stn and padding before a section are reported but kept
+	tests := []struct {
+		name     string
+		file     testFile
+		warnings int
+	}{
+		{
+			name: "v0200",
+			file: testFile{
+				version:   "0200",
+				playitems: [][]byte{testPlayItem("00001", 0, 180000, nil, stn), testPlayItem("00002", 90000, 270000, nil, stn)},
+				subpaths:  [][]byte{testSubPath(SPTTextSubtitle, testSubPlayItem("00010", 0, 0))},
+				padding:   [4][]byte{{0, 0}, {0xFF}},
+				reserved:  0x33,
+			},
+			warnings: 4,
+		},
+		{
+			name: "multi-angle",
+			file: testFile{
+				version:   "0200",
+				playitems: [][]byte{testPlayItem("00001", 0, 180000, []string{"00002", "00003"}, stn), testPlayItem("00004", 0, 90000, nil, stn)},
+			},
+			warnings: 2,
+		},
+		{
+			name: "v0300",
+			file: testFile{
+				version:   "0300",
+				playitems: [][]byte{testPlayItem("00001", 0, 180000, nil, testSTN{dv: true}.bytes()), testPlayItem("00002", 0, 90000, nil, testSTN{dv: true}.bytes())},
+				extension: testExtensionData([]testExtEntry{{id1: 3, id2: 5, data: testStaticMetadata()}}, []int{0}, nil, nil),
+				padding:   [4][]byte{nil, nil, {0, 0, 0, 0}, {0, 0}},
+			},
+		},
+		{
+			// the data of the SubPaths entry comes before the STN_table_SS data although it is second in the table
+			name: "3D",
+			file: testFile{
+				version:   "0200",
+				playitems: [][]byte{testPlayItem("00001", 0, 180000, nil, stn), testPlayItem("00002", 0, 90000, nil, stn)},
+				extension: testExtensionData([]testExtEntry{
+					{id1: 2, id2: 1, data: cat(testSTNSS(), testSTNSS()), padding: []byte{0, 0}},
+					{id1: 2, id2: 2, data: withLen32(cat(u16(1), testSubPath(SPTStereoscopicVideo, testSubPlayItem("00011", 0, 0), testSubPlayItem("00012", 1, 0))))},
+				}, []int{1, 0}, []byte{0, 0, 0, 0}, []byte{0x77}),
+			},
+			warnings: 2,
+		},
+		{
+			name: "PiP",
+			file: testFile{
+				version:   "0200",
+				playitems: [][]byte{testPlayItem("00001", 0, 180000, nil, testSTN{pip: true}.bytes()), testPlayItem("00002", 0, 90000, nil, testSTN{pip: true}.bytes())},
+				extension: testExtensionData([]testExtEntry{{id1: 1, id2: 1, data: testPiP()}}, []int{0}, nil, nil),
+			},
+		},
+		{
+			// zero length SubPaths and STN_table_SS entries stay empty
+			name: "empty entries",
+			file: testFile{
+				version:   "0200",
+				playitems: [][]byte{testPlayItem("00001", 0, 180000, nil, stn)},
+				extension: testExtensionData([]testExtEntry{
+					{id1: 2, id2: 1},
+					{id1: 2, id2: 2},
+					{id1: 3, id2: 5, data: testStaticMetadata()},
+				}, []int{0, 1, 2}, nil, nil),
+			},
+			warnings: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			roundTrip(t, test.file.bytes(), test.warnings)
+		})
+	}
+}
+
+func TestRoundTripDecoded(t *testing.T) {
+	file := testFile{
+		version:   "0200",
+		playitems: [][]byte{testPlayItem("00001", 0, 180000, nil, testSTN{pip: true}.bytes()), testPlayItem("00002", 0, 90000, nil, testSTN{pip: true}.bytes())},
+		extension: testExtensionData([]testExtEntry{
+			{id1: 2, id2: 1, data: cat(testSTNSS(), testSTNSS())},
+			{id1: 1, id2: 1, data: testPiP()},
+		}, []int{1, 0}, nil, nil),
+	}
+	mpls := roundTrip(t, file.bytes(), 0)
+
+	ss := mpls.Playlist.PlayItems[1].StreamTable.SS
+	if !ss.FixedOffsetDuringPopUp || len(ss.DependentViewStreams) != 1 || ss.DependentViewStreams[0].OffsetSequenceCount != 3 {
+		t.Errorf("STN_table_SS = %+v", ss)
+	}
+	if len(ss.PGStreams) != 1 || !ss.PGStreams[0].IsSS || ss.PGStreams[0].Right.PID != 0x1240 || ss.PGStreams[0].SSOffsetSequenceID != 2 {
+		t.Errorf("PG streams = %+v", ss.PGStreams)
+	}
+
+	pip := mpls.ExtensionData.PiPMetadata
+	if len(pip) != 2 || pip[1].PlayItemRef != 1 || !pip[1].TrickPlay || pip[1].Entries[1].X != 1820 || pip[1].Entries[1].Y != 0x040 {
+		t.Errorf("PiPMetadata = %+v", pip)
+	}
+}
@@ -0,0 +1,42 @@
+package mpls
+
+import (
+	"errors"
+	"fmt"
+
+	"timmy.narnian.us/mpls/internal/bdparse"
+)
+
+// Errors returned while parsing, wrapped in a *ParseError
+var (
+	ErrTruncated     = bdparse.ErrTruncated
+	ErrBadMagic      = bdparse.ErrBadMagic
+	ErrMisaligned    = bdparse.ErrMisaligned
+	ErrCountOverflow = bdparse.ErrCountOverflow
+)
+
+// ParseError records the section and byte offset where parsing failed.
+// Section is the path of the structure being parsed e.g. Playlist.PlayItems[2].StreamTable
+type ParseError = bdparse.ParseError
+
+// ErrInvalidValue is returned when a value can not be encoded, wrapped in an *EncodeError,
+// or when an edit can not be applied
+var ErrInvalidValue = errors.New("invalid value")
+
+// EncodeError records the section that could not be encoded.
+// Section is the path of the structure being encoded e.g. Playlist.PlayItems[2].StreamTable
+type EncodeError struct {
+	Section string
+	Err     error
+}
+
+func (e *EncodeError) Error() string {
+	if e.Section == "" {
+		return fmt.Sprintf("mpls: %v", e.Err)
+	}
+	return fmt.Sprintf("mpls: %s: %v", e.Section, e.Err)
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
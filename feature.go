@@ -0,0 +1,165 @@
+package mpls
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Main feature score weights, the score of a playlist is the sum of the points it gets for each
+const (
+	ScoreDuration  = 50.0  // scaled by the playlist's duration relative to the longest playlist
+	ScoreChapters  = 10.0  // full points at 20 chapters or more
+	ScoreLanguages = 10.0  // full points at 10 audio and subtitle languages or more
+	ScoreTitle     = 20.0  // referenced by a title in index.bdmv
+	ScoreReuse     = -10.0 // full penalty when every clip is shared with 10 or more other playlists
+	ScoreRevisit   = -15.0 // full penalty when half the PlayItems revisit an earlier clip
+)
+
+// Candidate is a playlist ranked as the main feature.
+// Reasons explains each part of Score
+type Candidate struct {
+	Name     string
+	Playlist *MPLS
+	Score    float64
+	Reasons  []string
+}
+
+// RankMainFeature scores playlists keyed by name as main feature candidates and returns them best first.
+// titles maps a playlist name to the index.bdmv titles playing it and may be nil
+func RankMainFeature(playlists map[string]*MPLS, titles map[string][]int) []Candidate {
+	var (
+		longest    int64
+		clipUsers  = map[string]int{}
+		candidates = make([]Candidate, 0, len(playlists))
+	)
+
+	for _, playlist := range playlists {
+		if ticks := playlist.Length(); ticks > longest {
+			longest = ticks
+		}
+		for _, clip := range playlist.Clips() {
+			clipUsers[clip]++
+		}
+	}
+
+	for name, playlist := range playlists {
+		c := Candidate{
+			Name:     name,
+			Playlist: playlist,
+		}
+		c.add(ScoreDuration*ratio(int(playlist.Length()), int(longest)), "duration %s is %.0f%% of the longest playlist", playlist.Duration.Round(time.Second), 100*ratio(int(playlist.Length()), int(longest)))
+
+		chapters := len(playlist.Chapters)
+		c.add(ScoreChapters*ratio(chapters, 20), "%d chapters", chapters)
+
+		audio, subtitles := playlist.languages()
+		c.add(ScoreLanguages*ratio(audio+subtitles, 10), "%d audio and %d subtitle languages", audio, subtitles)
+
+		if refs := titles[name]; len(refs) > 0 {
+			c.add(ScoreTitle, "played by title %v", refs)
+		}
+
+		clips := playlist.Clips()
+		shared := 0
+		for _, clip := range clips {
+			shared += clipUsers[clip] - 1
+		}
+		if len(clips) > 0 && shared > 0 {
+			average := float64(shared) / float64(len(clips))
+			c.add(ScoreReuse*ratio(shared, 10*len(clips)), "clips are shared with %.1f other playlists on average", average)
+		}
+
+		if revisits := playlist.revisits(); revisits > 0 {
+			c.add(ScoreRevisit*ratio(2*revisits, len(playlist.Playlist.PlayItems)), "%d PlayItems revisit an earlier clip", revisits)
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates
+}
+
+// MainFeature ranks every playlist on the disc as a main feature candidate, best first.
+// Titles are taken from index.bdmv and MovieObject.bdmv when the disc has them
+func (d *Disc) MainFeature() ([]Candidate, error) {
+	playlists, _, err := d.Playlists()
+	if err != nil {
+		return nil, err
+	}
+
+	return RankMainFeature(playlists, d.titles()), nil
+}
+
+// titles maps the name of each playlist played by a title to the titles playing it.
+// It is empty if the disc has no usable index.bdmv
+func (d *Disc) titles() map[string][]int {
+	titles := map[string][]int{}
+	if idx, err := d.Index(); err == nil {
+		for n := 1; n <= len(idx.Titles); n++ {
+			names, _ := d.TitlePlaylists(n)
+			for _, name := range names {
+				titles[name] = append(titles[name], n)
+			}
+		}
+	}
+	return titles
+}
+
+// add adds points to the score with the reason for them
+func (c *Candidate) add(points float64, format string, a ...interface{}) {
+	c.Score += points
+	c.Reasons = append(c.Reasons, fmt.Sprintf("%+.1f ", points)+fmt.Sprintf(format, a...))
+}
+
+// ratio returns n/max clamped to [0, 1]
+func ratio(n, max int) float64 {
+	if max <= 0 || n <= 0 {
+		return 0
+	}
+	if n >= max {
+		return 1
+	}
+	return float64(n) / float64(max)
+}
+
+// languages counts the distinct audio and subtitle languages of the playlist's PlayItems
+func (mpls *MPLS) languages() (audio, subtitles int) {
+	var (
+		audioLangs    = map[string]bool{}
+		subtitleLangs = map[string]bool{}
+	)
+	for _, playitem := range mpls.Playlist.PlayItems {
+		for _, stream := range playitem.StreamTable.PrimaryAudioStreams {
+			audioLangs[stream.Language] = true
+		}
+		for _, stream := range playitem.StreamTable.PrimaryPGStreams {
+			subtitleLangs[stream.Language] = true
+		}
+	}
+	return len(audioLangs), len(subtitleLangs)
+}
+
+// revisits counts the PlayItems that play a clip already played by an earlier, non adjacent, PlayItem
+func (mpls *MPLS) revisits() int {
+	var (
+		revisits int
+		seen     = map[string]bool{}
+		previous string
+	)
+	for _, playitem := range mpls.Playlist.PlayItems {
+		clip := playitem.Clpi.ClipFile
+		if seen[clip] && clip != previous {
+			revisits++
+		}
+		seen[clip] = true
+		previous = clip
+	}
+	return revisits
+}
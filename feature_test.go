@@ -0,0 +1,88 @@
+package mpls
+
+import (
+	"reflect"
+	"testing"
+)
+
+// featurePlaylist returns a playlist of PlayItems of the given clips and lengths in seconds
+// with an English audio and subtitle stream and chapters entry marks spread over the first PlayItem
+func featurePlaylist(clips []string, seconds []Ticks, chapters int) *MPLS {
+	mpls := &MPLS{}
+	for i, clip := range clips {
+		mpls.Playlist.PlayItems = append(mpls.Playlist.PlayItems, PlayItem{
+			OutTime: seconds[i] * TicksPerSecond,
+			Clpi:    CLPI{ClipFile: clip, ClipID: "M2TS"},
+			StreamTable: STNTable{
+				PrimaryAudioStreams: []PrimaryStream{{StreamEntry: StreamEntry{Type: 1, PID: 0x1100}, StreamAttributes: StreamAttributes{Language: "eng"}}},
+				PrimaryPGStreams:    []PrimaryStream{{StreamEntry: StreamEntry{Type: 1, PID: 0x1200}, StreamAttributes: StreamAttributes{Language: "eng"}}},
+			},
+		})
+	}
+	for i := 0; i < chapters; i++ {
+		mpls.MarkPlaylist.Marks = append(mpls.MarkPlaylist.Marks, Mark{Type: MarkEntry, Time: Ticks(i) * seconds[0] / Ticks(chapters) * TicksPerSecond, PID: 0xFFFF})
+	}
+	mpls.update()
+	return mpls
+}
+
+func TestRankMainFeature(t *testing.T) {
+	playlists := map[string]*MPLS{
+		// the longest playlist with the most chapters, sharing its clip with the fragmented copy
+		"00800": featurePlaylist([]string{"00100"}, []Ticks{7200}, 20),
+		// as long, but going back and forth between two clips
+		"00801": featurePlaylist([]string{"00100", "00101", "00100", "00101"}, []Ticks{1800, 1800, 1800, 1800}, 4),
+		// shorter, played by a title
+		"00802": featurePlaylist([]string{"00102"}, []Ticks{6480}, 12),
+		// a short fragment
+		"00900": featurePlaylist([]string{"00103"}, []Ticks{30}, 1),
+	}
+
+	candidates := RankMainFeature(playlists, map[string][]int{"00802": {1}})
+	want := []struct {
+		name    string
+		reasons []string
+	}{
+		{"00802", []string{
+			"+45.0 duration 1h48m0s is 90% of the longest playlist",
+			"+6.0 12 chapters",
+			"+2.0 1 audio and 1 subtitle languages",
+			"+20.0 played by title [1]",
+		}},
+		{"00800", []string{
+			"+50.0 duration 2h0m0s is 100% of the longest playlist",
+			"+10.0 20 chapters",
+			"+2.0 1 audio and 1 subtitle languages",
+			"-1.0 clips are shared with 1.0 other playlists on average",
+		}},
+		{"00801", []string{
+			"+50.0 duration 2h0m0s is 100% of the longest playlist",
+			"+2.0 4 chapters",
+			"+2.0 1 audio and 1 subtitle languages",
+			"-0.5 clips are shared with 0.5 other playlists on average",
+			"-15.0 2 PlayItems revisit an earlier clip",
+		}},
+		{"00900", []string{
+			"+0.2 duration 30s is 0% of the longest playlist",
+			"+0.5 1 chapters",
+			"+2.0 1 audio and 1 subtitle languages",
+		}},
+	}
+
+	if len(candidates) != len(want) {
+		t.Fatalf("%d candidates, want %d", len(candidates), len(want))
+	}
+	scores := []float64{73, 61, 38.5, 50.0*30/7200 + 0.5 + 2}
+	for i, c := range candidates {
+		if c.Name != want[i].name {
+			t.Errorf("candidate %d is %s, want %s", i, c.Name, want[i].name)
+			continue
+		}
+		if c.Playlist != playlists[c.Name] || c.Score != scores[i] {
+			t.Errorf("%s scored %v, want %v", c.Name, c.Score, scores[i])
+		}
+		if !reflect.DeepEqual(c.Reasons, want[i].reasons) {
+			t.Errorf("%s reasons = %q, want %q", c.Name, c.Reasons, want[i].reasons)
+		}
+	}
+}
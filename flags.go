@@ -0,0 +1,204 @@
+package mpls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UserOperations is a decoded UO mask table, a true field means the operation is prohibited
+type UserOperations struct {
+	MenuCall                    bool
+	TitleSearch                 bool
+	ChapterSearch               bool
+	TimeSearch                  bool
+	SkipToNextPoint             bool
+	SkipBackToPreviousPoint     bool
+	PlayFirstPlay               bool
+	Stop                        bool
+	PauseOn                     bool
+	PauseOff                    bool
+	StillOff                    bool
+	ForwardPlay                 bool
+	BackwardPlay                bool
+	Resume                      bool
+	MoveUpSelectedButton        bool
+	MoveDownSelectedButton      bool
+	MoveLeftSelectedButton      bool
+	MoveRightSelectedButton     bool
+	SelectButton                bool
+	ActivateButton              bool
+	SelectAndActivateButton     bool
+	AudioChange                 bool
+	AngleChange                 bool
+	PopupOn                     bool
+	PopupOff                    bool
+	PgTextstEnableDisable       bool
+	PgTextstChange              bool
+	SecondaryVideoEnableDisable bool
+	SecondaryVideoChange        bool
+	SecondaryAudioEnableDisable bool
+	SecondaryAudioChange        bool
+	PiPPgTextstChange           bool
+}
+
+// userOperation pairs a UO mask with its name and field in UserOperations
+type userOperation struct {
+	mask uint64
+	name string
+	set  *bool
+}
+
+// operations returns the user operations of uo in on disc order
+func (uo *UserOperations) operations() []userOperation {
+	return []userOperation{
+		{UOMenuCallMask, "MenuCall", &uo.MenuCall},
+		{UOTitleSearchMask, "TitleSearch", &uo.TitleSearch},
+		{UOChapterSearchMask, "ChapterSearch", &uo.ChapterSearch},
+		{UOTimeSearchMask, "TimeSearch", &uo.TimeSearch},
+		{UOSkipToNextPointMask, "SkipToNextPoint", &uo.SkipToNextPoint},
+		{UOSkipBackToPreviousPointMask, "SkipBackToPreviousPoint", &uo.SkipBackToPreviousPoint},
+		{UOPlayMask, "PlayFirstPlay", &uo.PlayFirstPlay},
+		{UOStopMask, "Stop", &uo.Stop},
+		{UOPauseOnMask, "PauseOn", &uo.PauseOn},
+		{UOPauseOffMask, "PauseOff", &uo.PauseOff},
+		{UOStillOffMask, "StillOff", &uo.StillOff},
+		{UOForwardPlayMask, "ForwardPlay", &uo.ForwardPlay},
+		{UOBackwardPlayMask, "BackwardPlay", &uo.BackwardPlay},
+		{UOResumeMask, "Resume", &uo.Resume},
+		{UOMoveUpSelectedButtonMask, "MoveUpSelectedButton", &uo.MoveUpSelectedButton},
+		{UOMoveDownSelectedButtonMask, "MoveDownSelectedButton", &uo.MoveDownSelectedButton},
+		{UOMoveLeftSelectedButtonMask, "MoveLeftSelectedButton", &uo.MoveLeftSelectedButton},
+		{UOMoveRightSelectedButtonMask, "MoveRightSelectedButton", &uo.MoveRightSelectedButton},
+		{UOSelectButtonMask, "SelectButton", &uo.SelectButton},
+		{UOActivateAndActivateMask, "ActivateButton", &uo.ActivateButton},
+		{UOSelectAndActivateMask, "SelectAndActivateButton", &uo.SelectAndActivateButton},
+		{UOAudioChangeMask, "AudioChange", &uo.AudioChange},
+		{UOAngleChangeMask, "AngleChange", &uo.AngleChange},
+		{UOPopupOnMask, "PopupOn", &uo.PopupOn},
+		{UOPopupOffMask, "PopupOff", &uo.PopupOff},
+		{UOPgTextstEnableDisableMask, "PgTextstEnableDisable", &uo.PgTextstEnableDisable},
+		{UOPgTextstChangeMask, "PgTextstChange", &uo.PgTextstChange},
+		{UOSecondaryVideoEnableDisableMask, "SecondaryVideoEnableDisable", &uo.SecondaryVideoEnableDisable},
+		{UOSecondaryVideoChangeMask, "SecondaryVideoChange", &uo.SecondaryVideoChange},
+		{UOSecondaryAudioEnableDisableMask, "SecondaryAudioEnableDisable", &uo.SecondaryAudioEnableDisable},
+		{UOSecondaryAudioChangeMask, "SecondaryAudioChange", &uo.SecondaryAudioChange},
+		{UOPiPPgTextstChangeMask, "PiPPgTextstChange", &uo.PiPPgTextstChange},
+	}
+}
+
+// DecodeUOMask decodes a 64 bit UO mask table
+func DecodeUOMask(mask uint64) UserOperations {
+	var uo UserOperations
+	for _, op := range uo.operations() {
+		*op.set = mask&op.mask != 0
+	}
+	return uo
+}
+
+// Mask encodes uo back to a 64 bit UO mask table
+func (uo UserOperations) Mask() uint64 {
+	var mask uint64
+	for _, op := range uo.operations() {
+		if *op.set {
+			mask |= op.mask
+		}
+	}
+	return mask
+}
+
+// String lists the prohibited operations separated by commas, "none" if every operation is allowed
+func (uo UserOperations) String() string {
+	var prohibited []string
+	for _, op := range uo.operations() {
+		if *op.set {
+			prohibited = append(prohibited, op.name)
+		}
+	}
+	if len(prohibited) == 0 {
+		return "none"
+	}
+	return strings.Join(prohibited, ",")
+}
+
+// PlaylistFlags is the decoded PlaylistFlags field of AppInfoPlaylist
+type PlaylistFlags struct {
+	PlaylistRandomAccess      bool
+	AudioMixApp               bool
+	LosslessMayBypassMixer    bool
+	MVCBaseViewR              bool
+	SDRConversionNotification bool
+}
+
+// String lists the set flags separated by commas
+func (pf PlaylistFlags) String() string {
+	var set []string
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"PlaylistRandomAccess", pf.PlaylistRandomAccess},
+		{"AudioMixApp", pf.AudioMixApp},
+		{"LosslessMayBypassMixer", pf.LosslessMayBypassMixer},
+		{"MVCBaseViewR", pf.MVCBaseViewR},
+		{"SDRConversionNotification", pf.SDRConversionNotification},
+	} {
+		if flag.set {
+			set = append(set, flag.name)
+		}
+	}
+	return strings.Join(set, ",")
+}
+
+// PlayItemFlags is the decoded Flags and AngleFlags fields of a PlayItem
+type PlayItemFlags struct {
+	IsMultiAngle          bool
+	ConnectionCondition   byte
+	IsDifferentAudios     bool
+	IsSeamlessAngleChange bool
+}
+
+// String lists the set flags and the connection condition separated by commas
+func (pif PlayItemFlags) String() string {
+	set := []string{fmt.Sprintf("ConnectionCondition=%d", pif.ConnectionCondition)}
+	if pif.IsMultiAngle {
+		set = append(set, "IsMultiAngle")
+	}
+	if pif.IsDifferentAudios {
+		set = append(set, "IsDifferentAudios")
+	}
+	if pif.IsSeamlessAngleChange {
+		set = append(set, "IsSeamlessAngleChange")
+	}
+	return strings.Join(set, ",")
+}
+
+// DecodeUOMask decodes the UO mask of the playlist
+func (aip AppInfoPlaylist) DecodeUOMask() UserOperations {
+	return DecodeUOMask(aip.UOMask)
+}
+
+// DecodeFlags decodes the PlaylistFlags of the playlist
+func (aip AppInfoPlaylist) DecodeFlags() PlaylistFlags {
+	return PlaylistFlags{
+		PlaylistRandomAccess:      aip.PlaylistFlags&PFPlaylistRandomAccess != 0,
+		AudioMixApp:               aip.PlaylistFlags&PFAudioMixApp != 0,
+		LosslessMayBypassMixer:    aip.PlaylistFlags&PFLosslessMayBypassMixer != 0,
+		MVCBaseViewR:              aip.PlaylistFlags&PFMVCBaseViewR != 0,
+		SDRConversionNotification: aip.PlaylistFlags&PFSDRConversionNotification != 0,
+	}
+}
+
+// DecodeUOMask decodes the UO mask of the PlayItem
+func (pi PlayItem) DecodeUOMask() UserOperations {
+	return DecodeUOMask(pi.UOMask)
+}
+
+// DecodeFlags decodes the Flags and AngleFlags of the PlayItem
+func (pi PlayItem) DecodeFlags() PlayItemFlags {
+	return PlayItemFlags{
+		IsMultiAngle:          pi.Flags&PIFIsMultiAngle != 0,
+		ConnectionCondition:   byte(pi.Flags & PIFConnectionCondition),
+		IsDifferentAudios:     pi.AngleFlags&AFIsDifferentAudios != 0,
+		IsSeamlessAngleChange: pi.AngleFlags&AFIsSeamlessAngleChange != 0,
+	}
+}
@@ -0,0 +1,68 @@
+package mpls
+
+import "testing"
+
+// uoBits are the bits of the UO mask table counted from the least significant bit, 41 and 31 are reserved
+var uoBits = map[string]uint{
+	"MenuCall": 63, "TitleSearch": 62, "ChapterSearch": 61, "TimeSearch": 60,
+	"SkipToNextPoint": 59, "SkipBackToPreviousPoint": 58, "PlayFirstPlay": 57, "Stop": 56,
+	"PauseOn": 55, "PauseOff": 54, "StillOff": 53, "ForwardPlay": 52, "BackwardPlay": 51, "Resume": 50,
+	"MoveUpSelectedButton": 49, "MoveDownSelectedButton": 48, "MoveLeftSelectedButton": 47, "MoveRightSelectedButton": 46,
+	"SelectButton": 45, "ActivateButton": 44, "SelectAndActivateButton": 43, "AudioChange": 42,
+	"AngleChange": 40, "PopupOn": 39, "PopupOff": 38, "PgTextstEnableDisable": 37, "PgTextstChange": 36,
+	"SecondaryVideoEnableDisable": 35, "SecondaryVideoChange": 34, "SecondaryAudioEnableDisable": 33, "SecondaryAudioChange": 32,
+	"PiPPgTextstChange": 30,
+}
+
+func TestDecodeUOMaskBits(t *testing.T) {
+	var uo UserOperations
+	if len(uo.operations()) != len(uoBits) {
+		t.Fatalf("%d operations, want %d", len(uo.operations()), len(uoBits))
+	}
+	for name, bit := range uoBits {
+		if s := DecodeUOMask(1 << bit).String(); s != name {
+			t.Errorf("bit %d decoded to %q, want %q", bit, s, name)
+		}
+	}
+	if uo := DecodeUOMask(1 << 63); !uo.MenuCall || uo.String() != "MenuCall" {
+		t.Errorf("bit 63 decoded to %+v, want MenuCall", uo)
+	}
+	if s := DecodeUOMask(0).String(); s != "none" {
+		t.Errorf("empty mask = %q, want none", s)
+	}
+}
+
+func TestUOMaskRoundTrip(t *testing.T) {
+	var defined uint64
+	for _, bit := range uoBits {
+		defined |= 1 << bit
+	}
+
+	for _, mask := range []uint64{0, defined, 1 << 63, 1<<61 | 1<<42 | 1<<30, 0xAAAAAAAAAAAAAAAA & defined, 0x5555555555555555 & defined} {
+		if got := DecodeUOMask(mask).Mask(); got != mask {
+			t.Errorf("DecodeUOMask(%#016x).Mask() = %#016x", mask, got)
+		}
+	}
+	// reserved bits are not decoded and are dropped by Mask
+	if got := DecodeUOMask(^uint64(0)).Mask(); got != defined {
+		t.Errorf("DecodeUOMask of every bit .Mask() = %#016x, want %#016x", got, defined)
+	}
+
+	uo := DecodeUOMask(1<<61 | 1<<42)
+	if !uo.ChapterSearch || !uo.AudioChange || uo.String() != "ChapterSearch,AudioChange" {
+		t.Errorf("chapter search and audio change mask decoded to %v", uo)
+	}
+}
+
+func TestDecodeFlags(t *testing.T) {
+	aip := AppInfoPlaylist{PlaylistFlags: PFPlaylistRandomAccess | PFMVCBaseViewR}
+	if flags := aip.DecodeFlags(); flags != (PlaylistFlags{PlaylistRandomAccess: true, MVCBaseViewR: true}) || flags.String() != "PlaylistRandomAccess,MVCBaseViewR" {
+		t.Errorf("playlist flags = %+v %q", flags, flags)
+	}
+
+	pi := PlayItem{Flags: PIFIsMultiAngle | CCSeamless, AngleFlags: AFIsSeamlessAngleChange}
+	want := PlayItemFlags{IsMultiAngle: true, ConnectionCondition: CCSeamless, IsSeamlessAngleChange: true}
+	if flags := pi.DecodeFlags(); flags != want || flags.String() != "ConnectionCondition=5,IsMultiAngle,IsSeamlessAngleChange" {
+		t.Errorf("play item flags = %+v %q, want %+v", flags, flags, want)
+	}
+}
@@ -0,0 +1,25 @@
+package index
+
+import "timmy.narnian.us/mpls/internal/bdparse"
+
+// Errors returned while parsing, wrapped in a *ParseError
+var (
+	ErrTruncated     = bdparse.ErrTruncated
+	ErrBadMagic      = bdparse.ErrBadMagic
+	ErrMisaligned    = bdparse.ErrMisaligned
+	ErrCountOverflow = bdparse.ErrCountOverflow
+)
+
+// ParseError records the section and byte offset where parsing failed.
+// Section is the path of the structure being parsed e.g. Titles[3]
+type ParseError = bdparse.ParseError
+
+// Warning is a non-fatal problem found while parsing
+type Warning = bdparse.Warning
+
+// Warning kinds
+const (
+	WarnMisaligned  = bdparse.WarnMisaligned
+	WarnVersion     = bdparse.WarnVersion
+	WarnUnknownType = bdparse.WarnUnknownType
+)
@@ -0,0 +1,80 @@
+// Package index parses the BDMV/index.bdmv file that lists the titles of a disc
+package index
+
+// Object types
+const (
+	ObjectHDMV = 1
+	ObjectBDJ  = 2
+)
+
+// Playback types
+const (
+	PlaybackHDMVMovie       = 0
+	PlaybackHDMVInteractive = 1
+	PlaybackBDJMovie        = 2
+	PlaybackBDJInteractive  = 3
+)
+
+// Access types
+const (
+	AccessPermitted   = 0
+	AccessProhibited  = 1 // title search is prohibited
+	AccessUndisplayed = 2 // title search is prohibited and the title is hidden
+)
+
+// Index is a struct representing an index.bdmv file
+type Index struct {
+	FileType           string
+	Version            string
+	IndexesStart       int
+	ExtensionDataStart int
+	AppInfo            AppInfo
+	IndexesLen         int
+	FirstPlayback      Object
+	TopMenu            Object
+	Titles             []Title
+	Warnings           []Warning
+}
+
+// AppInfo holds the disc wide playback settings
+type AppInfo struct {
+	Len                         int
+	InitialOutputModePreference byte // 0 2D, 1 3D
+	ContentExistFlag            bool // stereoscopic content exists
+	InitialDynamicRangeType     byte
+	VideoFormat                 byte
+	FrameRate                   byte
+	UserData                    [32]byte
+}
+
+// Object references the movie object or BD-J object played for an entry of the index
+type Object struct {
+	Type          byte
+	PlaybackType  byte
+	MovieObjectID uint16 // HDMV objects
+	BDJOName      string // BD-J objects
+}
+
+// Title is a numbered title, Titles[0] is title 1
+type Title struct {
+	Object
+	AccessType byte
+}
+
+// IsHDMV reports whether the object is an HDMV movie object
+func (o Object) IsHDMV() bool {
+	return o.Type == ObjectHDMV
+}
+
+// IsBDJ reports whether the object is a BD-J object
+func (o Object) IsBDJ() bool {
+	return o.Type == ObjectBDJ
+}
+
+// Title returns the numbered title n, counting from 1
+func (idx *Index) Title(n int) (Title, bool) {
+	if n < 1 || n > len(idx.Titles) {
+		return Title{}, false
+	}
+	return idx.Titles[n-1], true
+}
@@ -0,0 +1,192 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"timmy.narnian.us/mpls/internal/bdparse"
+)
+
+// errReader is the shared reader used by the index parser
+type errReader struct {
+	bdparse.Reader
+}
+
+// Parse parses an index.bdmv file into an Index struct
+func Parse(reader io.Reader) (idx Index, err error) {
+	var (
+		file []byte
+	)
+
+	file, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return Index{}, err
+	}
+
+	err = idx.Parse(file)
+	return idx, err
+}
+
+// Parse reads index data from a byte slice
+func (idx *Index) Parse(file []byte) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+	)
+
+	reader := &errReader{
+		Reader: bdparse.Reader{
+			RS:     bytes.NewReader(file),
+			Format: "index",
+		},
+	}
+	defer func() {
+		idx.Warnings = reader.Warnings
+	}()
+
+	_, err = reader.Read(buf[:8])
+	if err != nil {
+		return err
+	}
+	str := string(buf[:8])
+	if str[:4] != "INDX" {
+		return reader.Fail(0, fmt.Errorf("%w: not an index file it must start with 'INDX' it started with '%s'", ErrBadMagic, str[:4]))
+	}
+	idx.FileType = str[:4]
+	idx.Version = str[4:8]
+
+	switch idx.Version {
+	case "0100", "0200", "0300":
+	default:
+		reader.Warn(WarnVersion, 4, "index may not work it is version %s", idx.Version)
+	}
+
+	idx.IndexesStart, _ = bdparse.ReadInt32(reader, buf[:])
+	idx.ExtensionDataStart, _ = bdparse.ReadInt32(reader, buf[:])
+
+	_, _ = reader.Seek(24, io.SeekCurrent)
+
+	reader.Enter("AppInfo")
+	err = idx.AppInfo.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+
+	reader.Enter("Indexes")
+	defer reader.Leave()
+	_, _ = reader.Seek(int64(idx.IndexesStart), io.SeekStart)
+
+	idx.IndexesLen, _ = bdparse.ReadInt32(reader, buf[:])
+
+	start = reader.Pos()
+
+	reader.Enter("FirstPlayback")
+	err = idx.FirstPlayback.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+
+	reader.Enter("TopMenu")
+	err = idx.TopMenu.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+
+	count, _ := bdparse.ReadUInt16(reader, buf[:])
+
+	err = reader.Count(int(count), 12, start+int64(idx.IndexesLen))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(count); i++ {
+		var title Title
+		reader.Enter("Titles[%d]", i)
+		err = title.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		idx.Titles = append(idx.Titles, title)
+	}
+
+	return reader.Align(start, int64(idx.IndexesLen), nil)
+}
+
+// parse reads AppInfo data from an *errReader
+func (ai *AppInfo) parse(reader *errReader) error {
+	var (
+		start int64
+		buf   [10]byte
+	)
+
+	ai.Len, _ = bdparse.ReadInt32(reader, buf[:])
+
+	start = reader.Pos()
+
+	_, _ = reader.Read(buf[:2])
+	ai.InitialOutputModePreference = buf[0] >> 6 & 0x01
+	ai.ContentExistFlag = buf[0]&0x20 != 0
+	ai.InitialDynamicRangeType = buf[0] & 0x0F
+	ai.VideoFormat = buf[1] >> 4
+	ai.FrameRate = buf[1] & 0x0F
+
+	_, _ = reader.Read(ai.UserData[:])
+
+	return reader.Align(start, int64(ai.Len), nil)
+}
+
+// parse reads Title data from an *errReader
+func (t *Title) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+	)
+
+	_, _ = reader.Read(buf[:4])
+	t.Type = buf[0] >> 6
+	t.AccessType = buf[0] >> 4 & 0x03
+
+	return t.Object.parseRef(reader)
+}
+
+// parse reads the object of the FirstPlayback and TopMenu entries from an *errReader
+func (o *Object) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+	)
+
+	_, _ = reader.Read(buf[:4])
+	o.Type = buf[0] >> 6
+
+	return o.parseRef(reader)
+}
+
+// parseRef reads the 8 byte reference to the movie object or BD-J object of o
+func (o *Object) parseRef(reader *errReader) error {
+	var (
+		buf   [10]byte
+		start int64
+	)
+
+	start = reader.Pos()
+	_, _ = reader.Read(buf[:8])
+	o.PlaybackType = buf[0] >> 6
+
+	switch o.Type {
+	case ObjectHDMV:
+		o.MovieObjectID = binary.BigEndian.Uint16(buf[2:4])
+	case ObjectBDJ:
+		o.BDJOName = string(buf[2:7])
+	default:
+		reader.Warn(WarnUnknownType, start, "unknown object type %d", o.Type)
+	}
+
+	return reader.Err
+}
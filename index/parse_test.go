@@ -0,0 +1,124 @@
+package index
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	. "timmy.narnian.us/mpls/internal/bdtest"
+)
+
+func hdmvRef(playback byte, id uint16) []byte {
+	return Cat(U8(playback<<6), U8(0), U16(id), Fill(4, 0))
+}
+
+func bdjRef(playback byte, name string) []byte {
+	return Cat(U8(playback<<6), U8(0), []byte(name), U8(0))
+}
+
+// testFile lays out an index with 3D output preferred, an HDMV first playback, a BD-J top menu,
+// an HDMV title that cannot be searched for and a BD-J title
+func testFile(version string) []byte {
+	appInfo := Len32(Cat(
+		U8(0x40|0x20|0x02), U8(6<<4|4),
+		[]byte("user data for the disc authoring"),
+	))
+	indexes := Len32(Cat(
+		U8(ObjectHDMV<<6), Fill(3, 0), hdmvRef(PlaybackHDMVMovie, 0),
+		U8(ObjectBDJ<<6), Fill(3, 0), bdjRef(PlaybackBDJInteractive, "00000"),
+		U16(2),
+		U8(ObjectHDMV<<6|AccessProhibited<<4), Fill(3, 0), hdmvRef(PlaybackHDMVInteractive, 7),
+		U8(ObjectBDJ<<6|AccessUndisplayed<<4), Fill(3, 0), bdjRef(PlaybackBDJMovie, "00001"),
+	))
+
+	return Cat(
+		[]byte("INDX"+version),
+		U32(uint32(40+len(appInfo))), U32(0),
+		Fill(24, 0),
+		appInfo, indexes,
+	)
+}
+
+func TestParse(t *testing.T) {
+	var idx Index
+	if err := idx.Parse(testFile("0200")); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Warnings) != 0 {
+		t.Errorf("warnings: %v", idx.Warnings)
+	}
+
+	ai := idx.AppInfo
+	if ai.InitialOutputModePreference != 1 || !ai.ContentExistFlag || ai.InitialDynamicRangeType != 2 || ai.VideoFormat != 6 || ai.FrameRate != 4 {
+		t.Errorf("AppInfo = %+v", ai)
+	}
+	if string(ai.UserData[:]) != "user data for the disc authoring" {
+		t.Errorf("UserData = %q", ai.UserData)
+	}
+
+	if want := (Object{Type: ObjectHDMV, PlaybackType: PlaybackHDMVMovie}); idx.FirstPlayback != want || !idx.FirstPlayback.IsHDMV() {
+		t.Errorf("FirstPlayback = %+v, want %+v", idx.FirstPlayback, want)
+	}
+	if want := (Object{Type: ObjectBDJ, PlaybackType: PlaybackBDJInteractive, BDJOName: "00000"}); idx.TopMenu != want || !idx.TopMenu.IsBDJ() {
+		t.Errorf("TopMenu = %+v, want %+v", idx.TopMenu, want)
+	}
+
+	titles := []Title{
+		{Object: Object{Type: ObjectHDMV, PlaybackType: PlaybackHDMVInteractive, MovieObjectID: 7}, AccessType: AccessProhibited},
+		{Object: Object{Type: ObjectBDJ, PlaybackType: PlaybackBDJMovie, BDJOName: "00001"}, AccessType: AccessUndisplayed},
+	}
+	if !reflect.DeepEqual(idx.Titles, titles) {
+		t.Errorf("Titles = %+v, want %+v", idx.Titles, titles)
+	}
+	if title, ok := idx.Title(2); !ok || title != titles[1] {
+		t.Errorf("Title(2) = %+v %v, want %+v", title, ok, titles[1])
+	}
+	if _, ok := idx.Title(0); ok {
+		t.Error("Title(0) was found, titles count from 1")
+	}
+}
+
+func TestParseTitleCountOverflow(t *testing.T) {
+	file := testFile("0200")
+	start := int(binary.BigEndian.Uint32(file[8:12]))
+	// the count follows the length and the FirstPlayback and TopMenu entries
+	binary.BigEndian.PutUint16(file[start+4+2*12:], 3)
+
+	var idx Index
+	err := idx.Parse(file)
+	var parseErr *ParseError
+	if !errors.Is(err, ErrCountOverflow) || !errors.As(err, &parseErr) || parseErr.Section != "Indexes" {
+		t.Errorf("3 titles in the space of 2 returned %v, want ErrCountOverflow in Indexes", err)
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	file := testFile("0200")
+	for n := 0; n < len(file); n++ {
+		var idx Index
+		err := idx.Parse(file[:n])
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || !(errors.Is(err, ErrTruncated) || errors.Is(err, ErrCountOverflow)) {
+			t.Fatalf("parsing %d of %d bytes returned %v, want a truncation error", n, len(file), err)
+		}
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	file := testFile("0900")
+	start := int(binary.BigEndian.Uint32(file[8:12]))
+	file[start+4] = 3 << 6
+
+	var idx Index
+	if err := idx.Parse(file); err != nil {
+		t.Fatal(err)
+	}
+	want := []Warning{
+		{Kind: WarnVersion, Expected: 4, Actual: 8, Message: "index may not work it is version 0900"},
+		{Kind: WarnUnknownType, Section: "Indexes.FirstPlayback", Expected: int64(start + 8), Actual: int64(start + 16), Message: "unknown object type 3"},
+	}
+	if !reflect.DeepEqual(idx.Warnings, want) {
+		t.Errorf("Warnings = %+v, want %+v", idx.Warnings, want)
+	}
+}
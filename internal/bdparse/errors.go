@@ -0,0 +1,61 @@
+package bdparse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned while parsing, wrapped in a *ParseError
+var (
+	ErrTruncated     = errors.New("truncated")
+	ErrBadMagic      = errors.New("bad magic")
+	ErrMisaligned    = errors.New("misaligned")
+	ErrCountOverflow = errors.New("count overflow")
+)
+
+// ParseError records the section and byte offset where parsing failed.
+// Format is the name of the format being parsed e.g. mpls,
+// Section is the path of the structure being parsed e.g. Playlist.PlayItems[2].StreamTable
+type ParseError struct {
+	Format  string
+	Section string
+	Offset  int64
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	if e.Section == "" {
+		return fmt.Sprintf("%s: %v at offset %d", e.Format, e.Err, e.Offset)
+	}
+	return fmt.Sprintf("%s: %s: %v at offset %d", e.Format, e.Section, e.Err, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Warning kinds used by the formats that do not define their own.
+// WarnMisaligned is the Kind of the warnings recorded by Align in every format
+const (
+	WarnMisaligned  = iota // a section did not end where its length says it should
+	WarnVersion            // the file version is not one that is known to work
+	WarnUnknownType        // a field has a type that is not known
+)
+
+// Warning is a non-fatal problem found while parsing.
+// Kind is given by the caller of Warn.
+// Expected and Actual are byte offsets in the file
+type Warning struct {
+	Kind     int
+	Section  string
+	Expected int64
+	Actual   int64
+	Message  string
+}
+
+func (w Warning) String() string {
+	if w.Message != "" {
+		return fmt.Sprintf("%s: %s", w.Section, w.Message)
+	}
+	return fmt.Sprintf("%s: not aligned: current position is %d position should be %d", w.Section, w.Actual, w.Expected)
+}
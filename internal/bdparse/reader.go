@@ -0,0 +1,202 @@
+// Package bdparse holds the reader shared by the parsers of the Blu-ray file formats.
+// The reader keeps the first error, every read after it is a no-op returning that error,
+// so a parser can check for errors once per section
+package bdparse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Source is what a Reader reads from, *bytes.Reader is one
+type Source interface {
+	io.ReadSeeker
+	Size() int64
+}
+
+// Reader reads a file recording the first error and any warnings.
+// Base is the offset of RS in the file, offsets in errors and warnings are offsets in the file
+type Reader struct {
+	RS       Source
+	Err      error
+	Base     int64
+	Format   string // name of the format used in errors e.g. mpls
+	Path     []string
+	Warnings []Warning
+}
+
+func (er *Reader) Read(p []byte) (n int, err error) {
+	if er.Err != nil {
+		return 0, er.Err
+	}
+
+	start := er.Pos()
+	n, err = io.ReadFull(er.RS, p)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return n, er.Fail(start, fmt.Errorf("%w: read %d of %d bytes", ErrTruncated, n, len(p)))
+	case err != nil:
+		return n, er.Fail(start, err)
+	}
+
+	return n, nil
+}
+
+func (er *Reader) Seek(offset int64, whence int) (int64, error) {
+	if er.Err != nil {
+		return 0, er.Err
+	}
+
+	n64, err := er.RS.Seek(offset, whence)
+	if err != nil {
+		return 0, er.Fail(er.Pos(), err)
+	}
+
+	return n64, nil
+}
+
+// Enter pushes a section onto the path used to report warnings and errors
+func (er *Reader) Enter(format string, a ...interface{}) {
+	er.Path = append(er.Path, fmt.Sprintf(format, a...))
+}
+
+// Leave pops the last section entered
+func (er *Reader) Leave() {
+	er.Path = er.Path[:len(er.Path)-1]
+}
+
+// Section returns the path of the current section
+func (er *Reader) Section() string {
+	return strings.Join(er.Path, ".")
+}
+
+// Fail records err for the current section at offset unless an error has already been recorded
+func (er *Reader) Fail(offset int64, err error) error {
+	if er.Err == nil {
+		er.Err = &ParseError{
+			Format:  er.Format,
+			Section: er.Section(),
+			Offset:  er.Base + offset,
+			Err:     err,
+		}
+	}
+	return er.Err
+}
+
+// Warn records a warning for the current section at the current position
+func (er *Reader) Warn(kind int, expected int64, format string, a ...interface{}) {
+	er.Warnings = append(er.Warnings, Warning{
+		Kind:     kind,
+		Section:  er.Section(),
+		Expected: er.Base + expected,
+		Actual:   er.Base + er.Pos(),
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// Pos returns the current position
+func (er *Reader) Pos() int64 {
+	n64, _ := er.RS.Seek(0, io.SeekCurrent)
+	return n64
+}
+
+// Align checks that the current section ended length bytes after start.
+// Reading past the end is an error, bytes left over are read into extra with a warning,
+// or skipped if extra is nil
+func (er *Reader) Align(start, length int64, extra *[]byte) error {
+	if er.Err != nil {
+		return er.Err
+	}
+
+	end := er.Pos()
+	switch {
+	case end > start+length:
+		return er.Fail(start, fmt.Errorf("%w: section of %d bytes ended at %d not %d", ErrMisaligned, length, er.Base+end, er.Base+start+length))
+	case end < start+length:
+		er.Warn(WarnMisaligned, start+length, "")
+		if extra == nil {
+			_, err := er.Seek(start+length, io.SeekStart)
+			return err
+		}
+		*extra = er.Bytes(start + length - end)
+		return er.Err
+	}
+	return nil
+}
+
+// Bytes reads n bytes, failing before allocating if there are not that many left
+func (er *Reader) Bytes(n int64) []byte {
+	if er.Err != nil {
+		return nil
+	}
+
+	if n < 0 || er.Pos()+n > er.RS.Size() {
+		_ = er.Fail(er.Pos(), fmt.Errorf("%w: %d bytes needed %d left", ErrTruncated, n, er.RS.Size()-er.Pos()))
+		return nil
+	}
+	buf := make([]byte, n)
+	_, _ = er.Read(buf)
+	return buf
+}
+
+// Gap reads the bytes between the current position and end, if any
+func (er *Reader) Gap(end int64) []byte {
+	if end > er.RS.Size() {
+		end = er.RS.Size()
+	}
+	if er.Err != nil || er.Pos() >= end {
+		return nil
+	}
+
+	return er.Bytes(end - er.Pos())
+}
+
+// Count checks that count entries of at least size bytes fit before end
+func (er *Reader) Count(count, size int, end int64) error {
+	if er.Err != nil {
+		return er.Err
+	}
+
+	if end > er.RS.Size() {
+		end = er.RS.Size()
+	}
+	if er.Pos()+int64(count)*int64(size) > end {
+		return er.Fail(er.Pos(), fmt.Errorf("%w: %d entries of at least %d bytes do not fit in %d bytes", ErrCountOverflow, count, size, end-er.Pos()))
+	}
+	return nil
+}
+
+// ReadUInt16 reads a big endian uint16 using buf
+func ReadUInt16(reader io.Reader, buf []byte) (uint16, error) {
+	n, err := reader.Read(buf[:2])
+	if err != nil || n != 2 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(buf[:2]), nil
+}
+
+// ReadInt32 reads a big endian uint32 as an int using buf
+func ReadInt32(reader io.Reader, buf []byte) (int, error) {
+	n, err := ReadUInt32(reader, buf)
+	return int(n), err
+}
+
+// ReadUInt32 reads a big endian uint32 using buf
+func ReadUInt32(reader io.Reader, buf []byte) (uint32, error) {
+	n, err := reader.Read(buf[:4])
+	if err != nil || n != 4 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(buf[:4]), nil
+}
+
+// ReadUInt64 reads a big endian uint64 using buf
+func ReadUInt64(reader io.Reader, buf []byte) (uint64, error) {
+	n, err := reader.Read(buf[:8])
+	if err != nil || n != 8 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:8]), nil
+}
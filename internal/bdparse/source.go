@@ -0,0 +1,104 @@
+package bdparse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// bufferSize is how much a Source returned by NewSource reads at a time
+const bufferSize = 4096
+
+// NewSource returns a Source reading the size bytes of r starting at off.
+// Reads go through a buffer so parsing a file reads it in blocks rather than field by field,
+// a *bytes.Reader is already in memory and is read directly
+func NewSource(r io.ReaderAt, off, size int64) Source {
+	if br, ok := r.(*bytes.Reader); ok {
+		return io.NewSectionReader(br, off, size)
+	}
+	return &bufferedSource{
+		r:    r,
+		off:  off,
+		size: size,
+	}
+}
+
+// bufferedSource reads a section of an io.ReaderAt through a buffer holding the bytes at start
+type bufferedSource struct {
+	r     io.ReaderAt
+	off   int64
+	size  int64
+	pos   int64
+	start int64
+	buf   []byte
+}
+
+func (bs *bufferedSource) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if bs.pos >= bs.size {
+			break
+		}
+		if bs.pos < bs.start || bs.pos >= bs.start+int64(len(bs.buf)) {
+			err := bs.fill()
+			if err != nil {
+				return n, err
+			}
+		}
+		copied := copy(p[n:], bs.buf[bs.pos-bs.start:])
+		n += copied
+		bs.pos += int64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fill reads the block at the current position into the buffer
+func (bs *bufferedSource) fill() error {
+	length := bs.size - bs.pos
+	if length > bufferSize {
+		length = bufferSize
+	}
+	if bs.buf == nil {
+		bs.buf = make([]byte, bufferSize)
+	}
+
+	n, err := bs.r.ReadAt(bs.buf[:length], bs.off+bs.pos)
+	bs.start, bs.buf = bs.pos, bs.buf[:n]
+	if int64(n) == length {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		// the underlying reader is shorter than size
+		bs.size = bs.pos + int64(n)
+		if n == 0 {
+			return io.EOF
+		}
+		return nil
+	}
+	return err
+}
+
+func (bs *bufferedSource) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += bs.pos
+	case io.SeekEnd:
+		offset += bs.size
+	default:
+		return 0, errors.New("bdparse: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("bdparse: negative position")
+	}
+	bs.pos = offset
+	return offset, nil
+}
+
+// Size returns the size of the section
+func (bs *bufferedSource) Size() int64 {
+	return bs.size
+}
@@ -0,0 +1,44 @@
+// Package bdtest holds the helpers the tests of the Blu-ray file format parsers use
+// to lay out files byte by byte
+package bdtest
+
+import "encoding/binary"
+
+// U8 returns v as a single byte
+func U8(v byte) []byte { return []byte{v} }
+
+// U16 returns v big endian
+func U16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+
+// U32 returns v big endian
+func U32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// U64 returns v big endian
+func U64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+// Cat concatenates parts
+func Cat(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
+
+// Len8 prefixes body with its length as a byte
+func Len8(body []byte) []byte { return Cat(U8(byte(len(body))), body) }
+
+// Len16 prefixes body with its length as a uint16
+func Len16(body []byte) []byte { return Cat(U16(uint16(len(body))), body) }
+
+// Len32 prefixes body with its length as a uint32
+func Len32(body []byte) []byte { return Cat(U32(uint32(len(body))), body) }
+
+// Fill returns n bytes of v
+func Fill(n int, v byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = v
+	}
+	return b
+}
@@ -0,0 +1,36 @@
+package mpls
+
+import "testing"
+
+func TestLanguageName(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"eng", "English"},
+		{"ENG", "English"},
+		{"jpn", "Japanese"},
+		// bibliographic and terminology codes of the same language
+		{"ger", "German"},
+		{"deu", "German"},
+		{"fre", "French"},
+		{"fra", "French"},
+		{"chi", "Chinese"},
+		{"zho", "Chinese"},
+		{"und", "Undetermined"},
+		{"zxx", "No linguistic content; Not applicable"},
+		// the qaa-qtz range is reserved for local use
+		{"qaa", "Reserved for local use"},
+		{"qtz", "Reserved for local use"},
+		{"QMA", "Reserved for local use"},
+		{"qua", ""},
+		{"", ""},
+		{"en", ""},
+		{"english", ""},
+	}
+	for _, test := range tests {
+		if name := LanguageName(test.code); name != test.want {
+			t.Errorf("LanguageName(%q) = %q, want %q", test.code, name, test.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package mpls
+
+// VideoLayer is a video stream played with the base video of a main path PlayItem to complete it,
+// such as a Dolby Vision enhancement layer or an MVC dependent view
+type VideoLayer struct {
+	PlayItem int      // index of the PlayItem in Playlist.PlayItems
+	Clips    []string // clips carrying the layer, the PlayItem clip if it is muxed with the base video
+	PIDs     []uint16
+}
+
+// hasVideoLayer reports whether streams returns entries for a PlayItem or the playlist has a SubPath of type typ
+func (mpls *MPLS) hasVideoLayer(streams func(stnt STNTable) []StreamEntry, typ SubPathType) bool {
+	for _, playitem := range mpls.Playlist.PlayItems {
+		if len(streams(playitem.StreamTable)) > 0 {
+			return true
+		}
+	}
+	return len(mpls.subPathsOfType(typ)) > 0
+}
+
+// videoLayers returns the layer of every main path PlayItem that has one.
+// The layers are found through the entries streams returns for the STN table of each PlayItem,
+// entries carried by a SubPath refer to one of subpaths.
+// PlayItems without entries fall back to the SubPaths of type typ of the playlist and its extension data,
+// the layer is then taken to be muxed with PID pid
+func (mpls *MPLS) videoLayers(streams func(stnt STNTable) []StreamEntry, subpaths []SubPath, typ SubPathType, pid uint16) []VideoLayer {
+	var layers []VideoLayer
+	for i, playitem := range mpls.Playlist.PlayItems {
+		clips, pids := mpls.subPathClips(i, streams(playitem.StreamTable), subpaths, typ, pid)
+		if len(clips) > 0 {
+			layers = append(layers, VideoLayer{
+				PlayItem: i,
+				Clips:    clips,
+				PIDs:     pids,
+			})
+		}
+	}
+	return layers
+}
+
+// streamEntries returns the stream entry of every stream
+func streamEntries(streams []PrimaryStream) []StreamEntry {
+	entries := make([]StreamEntry, 0, len(streams))
+	for _, stream := range streams {
+		entries = append(entries, stream.StreamEntry)
+	}
+	return entries
+}
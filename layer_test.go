@@ -0,0 +1,54 @@
+package mpls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVideoLayers(t *testing.T) {
+	spi := func(clip string) SubPlayItem {
+		return SubPlayItem{Clpi: CLPI{ClipFile: clip, ClipID: "M2TS"}}
+	}
+	mpls := &MPLS{}
+	mpls.Playlist.PlayItems = []PlayItem{{
+		Clpi: CLPI{ClipFile: "00001", ClipID: "M2TS"},
+		StreamTable: STNTable{
+			DolbyVisionStreams: []PrimaryStream{{StreamEntry: StreamEntry{Type: 2, PID: 0x1015, SubPathID: 1}}},
+			SS: STNTableSS{
+				DependentViewStreams: []DependentViewStream{{PrimaryStream: PrimaryStream{StreamEntry: StreamEntry{Type: 2, PID: 0x1012, SubPathID: 0}}}},
+			},
+		},
+	}, {
+		Clpi: CLPI{ClipFile: "00002", ClipID: "M2TS"},
+	}}
+	// SubPathID 0 of the STN_table_SS is the extension data SubPath, not the main path one of the same type
+	mpls.Playlist.SubPaths = []SubPath{
+		{Type: SPTStereoscopicVideo, SubPlayItems: []SubPlayItem{spi("00041")}},
+		{Type: SPTDolbyVisionEL, SubPlayItems: []SubPlayItem{spi("00021"), spi("00022")}},
+	}
+	mpls.ExtensionData.SubPaths = []SubPath{
+		{Type: SPTStereoscopicVideo, SubPlayItems: []SubPlayItem{spi("00031")}},
+	}
+	mpls.Playlist.SubPaths[1].SubPlayItems[1].PlayItemID = 1
+
+	if !mpls.HasDolbyVisionEL() || !mpls.IsStereoscopic() {
+		t.Errorf("HasDolbyVisionEL = %v, IsStereoscopic = %v, want both true", mpls.HasDolbyVisionEL(), mpls.IsStereoscopic())
+	}
+
+	// the second PlayItem has no entries and falls back to the SubPath with the default PID
+	layers := []VideoLayer{
+		{PlayItem: 0, Clips: []string{"00021"}, PIDs: []uint16{0x1015}},
+		{PlayItem: 1, Clips: []string{"00022"}, PIDs: []uint16{DolbyVisionELPID}},
+	}
+	if got := mpls.DolbyVisionEL(); !reflect.DeepEqual(got, layers) {
+		t.Errorf("DolbyVisionEL = %+v, want %+v", got, layers)
+	}
+
+	views := []DependentView{{
+		VideoLayer: VideoLayer{PlayItem: 0, Clips: []string{"00031"}, PIDs: []uint16{0x1012}},
+		SSIF:       "00001",
+	}}
+	if got := mpls.DependentViews(); !reflect.DeepEqual(got, views) {
+		t.Errorf("DependentViews = %+v, want %+v", got, views)
+	}
+}
@@ -0,0 +1,166 @@
+package mpls
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Playlist maze detection thresholds
+const (
+	MazeMinPlaylists   = 10                   // similar playlists with different clip orders needed to flag a maze
+	MazeMinPlayItems   = 10                   // PlayItems a playlist needs to be part of a maze
+	MazeShortPlayItem  = 120 * TicksPerSecond // PlayItems shorter than this are fragments
+	MazeShortRatio     = 0.5                  // fraction of PlayItems that must be fragments
+	MazeDurationMargin = 0.05                 // relative difference in duration of similar playlists
+	MazeClipOverlap    = 0.8                  // fraction of clips similar playlists share
+)
+
+// Maze is the result of looking for an obfuscated "playlist maze",
+// many playlists of similar length that play the same clip fragments in different orders
+type Maze struct {
+	Detected  bool
+	Playlists []string // the similar playlists, sorted
+	Probable  string   // the playlist most likely to be the real one
+	Reasons   []string
+}
+
+// DetectMaze looks for a playlist maze in playlists keyed by name.
+// titles maps a playlist name to the index.bdmv titles playing it and may be nil.
+// Playlists playing the same clips in the same order count once, so a disc of duplicates is not a maze.
+// A title reference picks the probable real playlist, otherwise the one playing its clips most in order is picked
+func DetectMaze(playlists map[string]*MPLS, titles map[string][]int) Maze {
+	var (
+		maze   Maze
+		names  []string
+		orders = map[string][]string{}
+		group  []string
+	)
+
+	for name, playlist := range playlists {
+		if len(playlist.Playlist.PlayItems) >= MazeMinPlayItems && playlist.shortRatio() >= MazeShortRatio {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// names keeps the first playlist of every clip order, orders its duplicates
+	unique := names[:0]
+	for _, name := range names {
+		order := strings.Join(playlists[name].SegmentMap, ",")
+		if len(orders[order]) == 0 {
+			unique = append(unique, name)
+		}
+		orders[order] = append(orders[order], name)
+	}
+	names = unique
+
+	for _, seed := range names {
+		var similar []string
+		for _, name := range names {
+			if similarPlaylists(playlists[seed], playlists[name]) {
+				similar = append(similar, name)
+			}
+		}
+		if len(similar) > len(group) {
+			group = similar
+		}
+	}
+
+	for _, name := range group {
+		maze.Playlists = append(maze.Playlists, orders[strings.Join(playlists[name].SegmentMap, ",")]...)
+	}
+	sort.Strings(maze.Playlists)
+	if len(group) < MazeMinPlaylists {
+		return maze
+	}
+
+	maze.Detected = true
+	maze.Reasons = append(maze.Reasons, fmt.Sprintf("%d playlists of similar length play the same clips in %d different orders", len(maze.Playlists), len(group)))
+
+	for _, name := range maze.Playlists {
+		if refs := titles[name]; len(refs) > 0 {
+			maze.Probable = name
+			maze.Reasons = append(maze.Reasons, fmt.Sprintf("%s is played by title %v", name, refs))
+			return maze
+		}
+	}
+
+	best := -1.0
+	for _, name := range group {
+		if order := playlists[name].clipOrder(); order > best {
+			best = order
+			maze.Probable = name
+		}
+	}
+	maze.Reasons = append(maze.Reasons, fmt.Sprintf("%s plays %.0f%% of its clips in order", maze.Probable, 100*best))
+	return maze
+}
+
+// DetectMaze looks for a playlist maze on the disc using the titles of index.bdmv when the disc has them
+func (d *Disc) DetectMaze() (Maze, error) {
+	playlists, _, err := d.Playlists()
+	if err != nil {
+		return Maze{}, err
+	}
+	return DetectMaze(playlists, d.titles()), nil
+}
+
+// similarPlaylists reports whether a and b have a similar duration and mostly the same clips
+func similarPlaylists(a, b *MPLS) bool {
+	ta, tb := float64(a.Length()), float64(b.Length())
+	if math.Abs(ta-tb) > MazeDurationMargin*math.Max(ta, tb) {
+		return false
+	}
+
+	var (
+		clips  = map[string]bool{}
+		shared int
+	)
+	for _, clip := range a.SegmentMap {
+		clips[clip] = true
+	}
+	union := len(clips)
+	seen := map[string]bool{}
+	for _, clip := range b.SegmentMap {
+		if seen[clip] {
+			continue
+		}
+		seen[clip] = true
+		if clips[clip] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return union > 0 && float64(shared)/float64(union) >= MazeClipOverlap
+}
+
+// shortRatio returns the fraction of PlayItems shorter than MazeShortPlayItem
+func (mpls *MPLS) shortRatio() float64 {
+	var short int
+	for _, playitem := range mpls.Playlist.PlayItems {
+		if playitem.Length() < MazeShortPlayItem {
+			short++
+		}
+	}
+	if len(mpls.Playlist.PlayItems) == 0 {
+		return 0
+	}
+	return float64(short) / float64(len(mpls.Playlist.PlayItems))
+}
+
+// clipOrder returns the fraction of PlayItems that play a clip numbered after the clip of the previous PlayItem
+func (mpls *MPLS) clipOrder() float64 {
+	if len(mpls.SegmentMap) < 2 {
+		return 1
+	}
+	var ordered int
+	for i := 1; i < len(mpls.SegmentMap); i++ {
+		if mpls.SegmentMap[i] > mpls.SegmentMap[i-1] {
+			ordered++
+		}
+	}
+	return float64(ordered) / float64(len(mpls.SegmentMap)-1)
+}
@@ -0,0 +1,92 @@
+package mpls
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// mazePlaylist returns a playlist of 30 second fragments of the clips 00000 to 00011 in the order
+// they are rotated to by rotate, 0 plays them in order
+func mazePlaylist(rotate int) *MPLS {
+	mpls := &MPLS{}
+	for i := 0; i < 12; i++ {
+		mpls.Playlist.PlayItems = append(mpls.Playlist.PlayItems, PlayItem{
+			InTime:  0,
+			OutTime: 30 * TicksPerSecond,
+			Clpi:    CLPI{ClipFile: fmt.Sprintf("%05d", (i+rotate)%12), ClipID: "M2TS"},
+		})
+	}
+	mpls.update()
+	return mpls
+}
+
+// mazePlaylists returns 12 playlists named 00800 to 00811, playlist 0080n is rotated by rotate(n)
+func mazePlaylists(rotate func(n int) int) (map[string]*MPLS, []string) {
+	var (
+		playlists = map[string]*MPLS{}
+		names     []string
+	)
+	for n := 0; n < 12; n++ {
+		name := fmt.Sprintf("%05d", 800+n)
+		playlists[name] = mazePlaylist(rotate(n))
+		names = append(names, name)
+	}
+	// a feature length playlist is not part of the maze
+	feature := &MPLS{}
+	feature.Playlist.PlayItems = []PlayItem{{OutTime: 7200 * TicksPerSecond, Clpi: CLPI{ClipFile: "00100", ClipID: "M2TS"}}}
+	feature.update()
+	playlists["00001"] = feature
+	return playlists, names
+}
+
+func TestDetectMaze(t *testing.T) {
+	// playlist 00807 plays the clips in order
+	playlists, names := mazePlaylists(func(n int) int { return (n + 5) % 12 })
+
+	maze := DetectMaze(playlists, nil)
+	if !maze.Detected || !reflect.DeepEqual(maze.Playlists, names) {
+		t.Fatalf("maze = %+v, want a maze of %v", maze, names)
+	}
+	if maze.Probable != "00807" {
+		t.Errorf("probable playlist = %s, want the one playing its clips in order", maze.Probable)
+	}
+	reasons := []string{
+		"12 playlists of similar length play the same clips in 12 different orders",
+		"00807 plays 100% of its clips in order",
+	}
+	if !reflect.DeepEqual(maze.Reasons, reasons) {
+		t.Errorf("reasons = %q, want %q", maze.Reasons, reasons)
+	}
+}
+
+func TestDetectMazeTitleReference(t *testing.T) {
+	playlists, _ := mazePlaylists(func(n int) int { return (n + 5) % 12 })
+
+	// a title playing a playlist wins over the order of the clips, the feature title does not count
+	maze := DetectMaze(playlists, map[string][]int{"00001": {1}, "00803": {2, 4}})
+	if !maze.Detected || maze.Probable != "00803" {
+		t.Fatalf("maze = %+v, want 00803 picked by its titles", maze)
+	}
+	if want := "00803 is played by title [2 4]"; maze.Reasons[len(maze.Reasons)-1] != want {
+		t.Errorf("reasons = %q, want the last to be %q", maze.Reasons, want)
+	}
+}
+
+func TestDetectMazeDuplicates(t *testing.T) {
+	// every playlist plays the clips in the same order, they are duplicates and not a maze
+	playlists, names := mazePlaylists(func(n int) int { return 3 })
+	maze := DetectMaze(playlists, nil)
+	if maze.Detected {
+		t.Errorf("duplicate playlists were flagged as a maze: %+v", maze)
+	}
+	if !reflect.DeepEqual(maze.Playlists, names) {
+		t.Errorf("playlists = %v, want %v", maze.Playlists, names)
+	}
+
+	// 8 different orders among 12 playlists are not enough
+	playlists, _ = mazePlaylists(func(n int) int { return n % 8 })
+	if maze = DetectMaze(playlists, nil); maze.Detected {
+		t.Errorf("8 clip orders were flagged as a maze: %+v", maze)
+	}
+}
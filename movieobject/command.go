@@ -0,0 +1,305 @@
+package movieobject
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Command groups
+const (
+	GroupBranch  = 0
+	GroupCompare = 1
+	GroupSet     = 2
+)
+
+// Branch sub-groups
+const (
+	BranchGoto = 0
+	BranchJump = 1
+	BranchPlay = 2
+)
+
+// Set sub-groups
+const (
+	SetSet    = 0
+	SetSystem = 1
+)
+
+// Opcode identifies the operation of a Command
+type Opcode int
+
+// Opcodes
+const (
+	OpUnknown Opcode = iota
+
+	OpNop
+	OpGoto
+	OpBreak
+
+	OpJumpObject
+	OpJumpTitle
+	OpCallObject
+	OpCallTitle
+	OpResume
+
+	OpPlayPL
+	OpPlayPLatPI
+	OpPlayPLatMK
+	OpTerminatePL
+	OpLinkPI
+	OpLinkMK
+
+	OpBC
+	OpEQ
+	OpNE
+	OpGE
+	OpGT
+	OpLE
+	OpLT
+
+	OpMove
+	OpSwap
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpRnd
+	OpAnd
+	OpOr
+	OpXor
+	OpBitSet
+	OpBitClr
+	OpShiftLeft
+	OpShiftRight
+
+	OpSetStream
+	OpSetNVTimer
+	OpSetButtonPage
+	OpEnableButton
+	OpDisableButton
+	OpSetSecondaryStream
+	OpPopUpMenuOff
+	OpStillOn
+	OpStillOff
+	OpSetOutputMode
+	OpSetStreamSS
+	OpSetSystem0x10
+)
+
+var opcodeNames = [...]string{
+	OpUnknown: "Unknown",
+
+	OpNop:   "Nop",
+	OpGoto:  "Goto",
+	OpBreak: "Break",
+
+	OpJumpObject: "JumpObject",
+	OpJumpTitle:  "JumpTitle",
+	OpCallObject: "CallObject",
+	OpCallTitle:  "CallTitle",
+	OpResume:     "Resume",
+
+	OpPlayPL:      "PlayPL",
+	OpPlayPLatPI:  "PlayPLatPI",
+	OpPlayPLatMK:  "PlayPLatMK",
+	OpTerminatePL: "TerminatePL",
+	OpLinkPI:      "LinkPI",
+	OpLinkMK:      "LinkMK",
+
+	OpBC: "BC",
+	OpEQ: "EQ",
+	OpNE: "NE",
+	OpGE: "GE",
+	OpGT: "GT",
+	OpLE: "LE",
+	OpLT: "LT",
+
+	OpMove:       "Move",
+	OpSwap:       "Swap",
+	OpAdd:        "Add",
+	OpSub:        "Sub",
+	OpMul:        "Mul",
+	OpDiv:        "Div",
+	OpMod:        "Mod",
+	OpRnd:        "Rnd",
+	OpAnd:        "And",
+	OpOr:         "Or",
+	OpXor:        "Xor",
+	OpBitSet:     "BitSet",
+	OpBitClr:     "BitClr",
+	OpShiftLeft:  "ShiftLeft",
+	OpShiftRight: "ShiftRight",
+
+	OpSetStream:          "SetStream",
+	OpSetNVTimer:         "SetNVTimer",
+	OpSetButtonPage:      "SetButtonPage",
+	OpEnableButton:       "EnableButton",
+	OpDisableButton:      "DisableButton",
+	OpSetSecondaryStream: "SetSecondaryStream",
+	OpPopUpMenuOff:       "PopUpMenuOff",
+	OpStillOn:            "StillOn",
+	OpStillOff:           "StillOff",
+	OpSetOutputMode:      "SetOutputMode",
+	OpSetStreamSS:        "SetStreamSS",
+	OpSetSystem0x10:      "SetSystem0x10",
+}
+
+func (op Opcode) String() string {
+	if op < 0 || int(op) >= len(opcodeNames) {
+		return fmt.Sprintf("Opcode(%d)", int(op))
+	}
+	return opcodeNames[op]
+}
+
+// options maps the option field of each group and sub-group to its opcode
+var options = map[[2]byte][]Opcode{
+	{GroupBranch, BranchGoto}: {OpNop, OpGoto, OpBreak},
+	{GroupBranch, BranchJump}: {OpJumpObject, OpJumpTitle, OpCallObject, OpCallTitle, OpResume},
+	{GroupBranch, BranchPlay}: {OpPlayPL, OpPlayPLatPI, OpPlayPLatMK, OpTerminatePL, OpLinkPI, OpLinkMK},
+	{GroupCompare, 0}:         {OpUnknown, OpBC, OpEQ, OpNE, OpGE, OpGT, OpLE, OpLT},
+	{GroupSet, SetSet}: {
+		OpUnknown, OpMove, OpSwap, OpAdd, OpSub, OpMul, OpDiv, OpMod,
+		OpRnd, OpAnd, OpOr, OpXor, OpBitSet, OpBitClr, OpShiftLeft, OpShiftRight,
+	},
+	{GroupSet, SetSystem}: {
+		OpUnknown, OpSetStream, OpSetNVTimer, OpSetButtonPage, OpEnableButton, OpDisableButton,
+		OpSetSecondaryStream, OpPopUpMenuOff, OpStillOn, OpStillOff, OpSetOutputMode, OpSetStreamSS,
+		OpUnknown, OpUnknown, OpUnknown, OpUnknown, OpSetSystem0x10,
+	},
+}
+
+// Command is a single 12 byte HDMV navigation command
+type Command struct {
+	OperandCount  byte
+	Group         byte
+	SubGroup      byte
+	BranchOption  byte
+	CompareOption byte
+	SetOption     byte
+	Dst           Operand
+	Src           Operand
+}
+
+// Operand is the destination or source of a Command.
+// If it is not immediate Value refers to a register
+type Operand struct {
+	Value     uint32
+	Immediate bool
+}
+
+// IsPSR reports whether the operand refers to a player status register
+func (o Operand) IsPSR() bool {
+	return !o.Immediate && o.Value&0x80000000 != 0
+}
+
+// Register returns the number of the general purpose or player status register the operand refers to
+func (o Operand) Register() int {
+	if o.IsPSR() {
+		return int(o.Value & 0x7F)
+	}
+	return int(o.Value & 0xFFF)
+}
+
+func (o Operand) String() string {
+	switch {
+	case o.Immediate && o.Value > 0xFFFF:
+		return fmt.Sprintf("0x%08X", o.Value)
+	case o.Immediate:
+		return fmt.Sprintf("%d", o.Value)
+	case o.IsPSR():
+		return fmt.Sprintf("PSR%d", o.Register())
+	default:
+		return fmt.Sprintf("r%d", o.Register())
+	}
+}
+
+// Op returns the opcode selected by the group, sub-group and option of the command
+func (c Command) Op() Opcode {
+	var (
+		option byte
+		key    = [2]byte{c.Group, c.SubGroup}
+	)
+
+	switch c.Group {
+	case GroupBranch:
+		option = c.BranchOption
+	case GroupCompare:
+		option = c.CompareOption
+		key[1] = 0
+	case GroupSet:
+		option = c.SetOption
+	}
+
+	ops := options[key]
+	if int(option) >= len(ops) {
+		return OpUnknown
+	}
+	return ops[option]
+}
+
+// Operands returns the operands used by the command
+func (c Command) Operands() []Operand {
+	switch {
+	case c.OperandCount >= 2:
+		return []Operand{c.Dst, c.Src}
+	case c.OperandCount == 1:
+		return []Operand{c.Dst}
+	}
+	return nil
+}
+
+// Playlist returns the playlist played by a PlayPL, PlayPLatPI or PlayPLatMK command
+// when it is given as an immediate value
+func (c Command) Playlist() (int, bool) {
+	switch c.Op() {
+	case OpPlayPL, OpPlayPLatPI, OpPlayPLatMK:
+		if c.OperandCount >= 1 && c.Dst.Immediate {
+			return int(c.Dst.Value), true
+		}
+	}
+	return 0, false
+}
+
+// String returns the command as a line of assembly e.g. "PlayPL 1"
+func (c Command) String() string {
+	var operands []string
+	for _, o := range c.Operands() {
+		operands = append(operands, o.String())
+	}
+
+	op := c.Op()
+	if op == OpUnknown {
+		return fmt.Sprintf("%-18s group=%d sub_group=%d branch=%d compare=%d set=%d %s", op, c.Group, c.SubGroup, c.BranchOption, c.CompareOption, c.SetOption, strings.Join(operands, ", "))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%-18s %s", op, strings.Join(operands, ", ")))
+}
+
+// String returns the commands of the object as an assembly listing, one numbered command per line
+func (o Object) String() string {
+	var b strings.Builder
+	for i, cmd := range o.Commands {
+		fmt.Fprintf(&b, "%4d: %s\n", i, cmd)
+	}
+	return b.String()
+}
+
+// Listing returns the assembly listing of every movie object
+func (mobj *MOBJ) Listing() string {
+	var b strings.Builder
+	for i, obj := range mobj.Objects {
+		fmt.Fprintf(&b, "Object %d:", i)
+		if obj.ResumeIntention {
+			b.WriteString(" resume")
+		}
+		if obj.MenuCallMask {
+			b.WriteString(" menu_call_mask")
+		}
+		if obj.TitleSearchMask {
+			b.WriteString(" title_search_mask")
+		}
+		b.WriteString("\n")
+		b.WriteString(obj.String())
+	}
+	return b.String()
+}
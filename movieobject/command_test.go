@@ -0,0 +1,129 @@
+package movieobject
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "timmy.narnian.us/mpls/internal/bdtest"
+)
+
+// commandTests are commands as they are stored in MovieObject.bdmv with the opcode and text they decode to
+var commandTests = []struct {
+	hex  string
+	op   Opcode
+	text string
+}{
+	{"000000000000000000000000", OpNop, "Nop"},
+	{"200100000000000300000000", OpGoto, "Goto               r3"},
+	{"208100000000000300000000", OpGoto, "Goto               3"},
+	{"000200000000000000000000", OpBreak, "Break"},
+	{"218000000000000400000000", OpJumpObject, "JumpObject         4"},
+	{"218100000000000200000000", OpJumpTitle, "JumpTitle          2"},
+	{"218200000000000100000000", OpCallObject, "CallObject         1"},
+	{"218300000000000700000000", OpCallTitle, "CallTitle          7"},
+	{"010400000000000000000000", OpResume, "Resume"},
+	{"228000000000000100000000", OpPlayPL, "PlayPL             1"},
+	{"220000000000000500000000", OpPlayPL, "PlayPL             r5"},
+	{"42c100000000000300000002", OpPlayPLatPI, "PlayPLatPI         3, 2"},
+	{"42c200000000000300000004", OpPlayPLatMK, "PlayPLatMK         3, 4"},
+	{"020300000000000000000000", OpTerminatePL, "TerminatePL"},
+	{"228400000000000100000000", OpLinkPI, "LinkPI             1"},
+	{"484002000000000000000005", OpEQ, "EQ                 r0, 5"},
+	{"484006000000000a000000ff", OpLE, "LE                 r10, 255"},
+	{"484001000000000100000010", OpBC, "BC                 r1, 16"},
+	{"504000010000000100000064", OpMove, "Move               r1, 100"},
+	{"500000010000000080000004", OpMove, "Move               r0, PSR4"},
+	{"504000030000000200000001", OpAdd, "Add                r2, 1"},
+	{"504000080000000300001000", OpRnd, "Rnd                r3, 4096"},
+	{"51c0000180010000000000c0", OpSetStream, "SetStream          0x80010000, 192"},
+	{"51c000038000000100000000", OpSetButtonPage, "SetButtonPage      0x80000001, 0"},
+	{"118000070000000000000000", OpPopUpMenuOff, "PopUpMenuOff"},
+	{"080000000000000000000000", OpUnknown, "Unknown            group=1 sub_group=0 branch=0 compare=0 set=0 "},
+}
+
+func decodeCommand(t *testing.T, s string) Command {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 12 {
+		t.Fatalf("bad test command %q", s)
+	}
+	var mobj MOBJ
+	if err = mobj.Parse(testFile([][]byte{b})); err != nil {
+		t.Fatal(err)
+	}
+	return mobj.Objects[0].Commands[0]
+}
+
+// testFile lays out a movie object file with one object for each list of commands
+func testFile(objects ...[][]byte) []byte {
+	body := Cat(Fill(4, 0), U16(uint16(len(objects))))
+	for i, commands := range objects {
+		body = Cat(body, U8(byte(i)<<6), U8(0), U16(uint16(len(commands))), Cat(commands...))
+	}
+	return Cat([]byte("MOBJ0200"), U32(0), Fill(28, 0), Len32(body))
+}
+
+func TestCommands(t *testing.T) {
+	for _, test := range commandTests {
+		cmd := decodeCommand(t, test.hex)
+		if cmd.Op() != test.op {
+			t.Errorf("%s decoded to %s, want %s", test.hex, cmd.Op(), test.op)
+		}
+		if cmd.String() != test.text {
+			t.Errorf("%s = %q, want %q", test.hex, cmd, test.text)
+		}
+	}
+}
+
+func TestPlaylists(t *testing.T) {
+	var commands [][]byte
+	for _, s := range []string{
+		"228000000000000100000000", // PlayPL 1
+		"220000000000000500000000", // PlayPL r5, not an immediate playlist
+		"218100000000000200000000", // JumpTitle 2
+		"42c100000000000300000002", // PlayPLatPI 3, 2
+		"42c200000000000400000004", // PlayPLatMK 4, 4
+	} {
+		b, _ := hex.DecodeString(s)
+		commands = append(commands, b)
+	}
+
+	var mobj MOBJ
+	if err := mobj.Parse(testFile(commands)); err != nil {
+		t.Fatal(err)
+	}
+	obj := mobj.Objects[0]
+	if want := []int{1, 3, 4}; !reflect.DeepEqual(obj.Playlists(), want) {
+		t.Errorf("Playlists = %v, want %v", obj.Playlists(), want)
+	}
+	if pl, ok := obj.Commands[1].Playlist(); ok {
+		t.Errorf("PlayPL through a register returned playlist %d", pl)
+	}
+	if _, ok := obj.Commands[2].Playlist(); ok {
+		t.Error("JumpTitle returned a playlist")
+	}
+}
+
+func TestListing(t *testing.T) {
+	play, _ := hex.DecodeString("228000000000000100000000")
+	jump, _ := hex.DecodeString("218100000000000200000000")
+	file := testFile([][]byte{play}, [][]byte{jump, play})
+	var mobj MOBJ
+	if err := mobj.Parse(file); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"Object 0:",
+		"   0: PlayPL             1",
+		"Object 1: menu_call_mask",
+		"   0: JumpTitle          2",
+		"   1: PlayPL             1",
+		"",
+	}, "\n")
+	if mobj.Listing() != want {
+		t.Errorf("Listing =\n%s\nwant\n%s", mobj.Listing(), want)
+	}
+}
@@ -0,0 +1,33 @@
+// Package movieobject parses the BDMV/MovieObject.bdmv file and disassembles
+// the HDMV navigation commands of its movie objects
+package movieobject
+
+// MOBJ is a struct representing a MovieObject.bdmv file
+type MOBJ struct {
+	FileType           string
+	Version            string
+	ExtensionDataStart int
+	Len                int
+	Objects            []Object
+	Warnings           []Warning
+}
+
+// Object is a single movie object, a title in index.bdmv references it by its index in MOBJ.Objects
+type Object struct {
+	ResumeIntention bool
+	MenuCallMask    bool // menu call is prohibited
+	TitleSearchMask bool // title search is prohibited
+	Commands        []Command
+}
+
+// Playlists returns the playlists the object plays with an immediate PlayPL, PlayPLatPI or PlayPLatMK
+// command in the order they appear. Playlists selected through a register are not included
+func (o Object) Playlists() []int {
+	var playlists []int
+	for _, cmd := range o.Commands {
+		if pl, ok := cmd.Playlist(); ok {
+			playlists = append(playlists, pl)
+		}
+	}
+	return playlists
+}
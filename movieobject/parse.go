@@ -0,0 +1,151 @@
+package movieobject
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"timmy.narnian.us/mpls/internal/bdparse"
+)
+
+// errReader is the shared reader used by the movie object parser
+type errReader struct {
+	bdparse.Reader
+}
+
+// Parse parses a MovieObject.bdmv file into a MOBJ struct
+func Parse(reader io.Reader) (mobj MOBJ, err error) {
+	var (
+		file []byte
+	)
+
+	file, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return MOBJ{}, err
+	}
+
+	err = mobj.Parse(file)
+	return mobj, err
+}
+
+// Parse reads movie object data from a byte slice
+func (mobj *MOBJ) Parse(file []byte) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+	)
+
+	reader := &errReader{
+		Reader: bdparse.Reader{
+			RS:     bytes.NewReader(file),
+			Format: "movieobject",
+		},
+	}
+	defer func() {
+		mobj.Warnings = reader.Warnings
+	}()
+
+	_, err = reader.Read(buf[:8])
+	if err != nil {
+		return err
+	}
+	str := string(buf[:8])
+	if str[:4] != "MOBJ" {
+		return reader.Fail(0, fmt.Errorf("%w: not a movie object file it must start with 'MOBJ' it started with '%s'", ErrBadMagic, str[:4]))
+	}
+	mobj.FileType = str[:4]
+	mobj.Version = str[4:8]
+
+	switch mobj.Version {
+	case "0100", "0200", "0300":
+	default:
+		reader.Warn(WarnVersion, 4, "movie objects may not work it is version %s", mobj.Version)
+	}
+
+	mobj.ExtensionDataStart, _ = bdparse.ReadInt32(reader, buf[:])
+
+	_, _ = reader.Seek(28, io.SeekCurrent)
+
+	mobj.Len, _ = bdparse.ReadInt32(reader, buf[:])
+
+	start = reader.Pos()
+
+	_, _ = reader.Seek(4, io.SeekCurrent)
+	count, _ := bdparse.ReadUInt16(reader, buf[:])
+
+	err = reader.Count(int(count), 4, start+int64(mobj.Len))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(count); i++ {
+		var obj Object
+		reader.Enter("Objects[%d]", i)
+		err = obj.parse(reader, start+int64(mobj.Len))
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		mobj.Objects = append(mobj.Objects, obj)
+	}
+
+	return reader.Align(start, int64(mobj.Len), nil)
+}
+
+// parse reads Object data from an *errReader
+func (o *Object) parse(reader *errReader, end int64) error {
+	var (
+		buf [10]byte
+		err error
+	)
+
+	_, _ = reader.Read(buf[:2])
+	o.ResumeIntention = buf[0]&0x80 != 0
+	o.MenuCallMask = buf[0]&0x40 != 0
+	o.TitleSearchMask = buf[0]&0x20 != 0
+
+	count, _ := bdparse.ReadUInt16(reader, buf[:])
+
+	err = reader.Count(int(count), 12, end)
+	if err != nil {
+		return err
+	}
+
+	o.Commands = make([]Command, 0, count)
+	for i := 0; i < int(count); i++ {
+		var cmd Command
+		reader.Enter("Commands[%d]", i)
+		err = cmd.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		o.Commands = append(o.Commands, cmd)
+	}
+
+	return reader.Err
+}
+
+// parse reads Command data from an *errReader
+func (c *Command) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+	)
+
+	_, _ = reader.Read(buf[:4])
+	c.OperandCount = buf[0] >> 5
+	c.Group = buf[0] >> 3 & 0x03
+	c.SubGroup = buf[0] & 0x07
+	c.Dst.Immediate = buf[1]&0x80 != 0
+	c.Src.Immediate = buf[1]&0x40 != 0
+	c.BranchOption = buf[1] & 0x0F
+	c.CompareOption = buf[2] & 0x0F
+	c.SetOption = buf[3] & 0x1F
+
+	c.Dst.Value, _ = bdparse.ReadUInt32(reader, buf[:])
+	c.Src.Value, _ = bdparse.ReadUInt32(reader, buf[:])
+
+	return reader.Err
+}
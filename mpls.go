@@ -1,79 +1,140 @@
 package mpls
 
-// User Operation mask table
+import "time"
+
+// User Operation mask table, bit 63 is the first bit of the 64 bit UO_mask_table on disc.
+// A set bit prohibits the operation
 const (
-	UOChapterSearchMask = 1 << iota
+	UOMenuCallMask uint64 = 1 << (63 - iota)
+	UOTitleSearchMask
+	UOChapterSearchMask
 	UOTimeSearchMask
 	UOSkipToNextPointMask
 	UOSkipBackToPreviousPointMask
-	UOForwardPlayMask
-	UOBackwardPlayMask
-	UOPlayMask
+	UOPlayMask // Play FirstPlay
 	UOStopMask
 	UOPauseOnMask
 	UOPauseOffMask
 	UOStillOffMask
+	UOForwardPlayMask
+	UOBackwardPlayMask
 	UOResumeMask
 	UOMoveUpSelectedButtonMask
 	UOMoveDownSelectedButtonMask
 	UOMoveLeftSelectedButtonMask
 	UOMoveRightSelectedButtonMask
 	UOSelectButtonMask
-	UOActivateAndActivateMask
+	UOActivateAndActivateMask // Activate Button
 	UOSelectAndActivateMask
 	UOAudioChangeMask
-	UOPgTextstChangeMask
+	_
 	UOAngleChangeMask
 	UOPopupOnMask
 	UOPopupOffMask
-	UOSelectMenuLanguageMask
+	UOPgTextstEnableDisableMask
+	UOPgTextstChangeMask
+	UOSecondaryVideoEnableDisableMask
+	UOSecondaryVideoChangeMask
+	UOSecondaryAudioEnableDisableMask
+	UOSecondaryAudioChangeMask
+	_
+	UOPiPPgTextstChangeMask
 )
 
-// Playlist Flags
+// Playlist Flags, bit 15 is the first bit of the 16 bit field on disc
 const (
-	PFPlaylistRandomAccess = 1 << iota
+	PFPlaylistRandomAccess uint16 = 1 << (15 - iota)
 	PFAudioMixApp
 	PFLosslessMayBypassMixer
-	PFreserved
+	PFMVCBaseViewR
+	PFSDRConversionNotification
+)
+
+// PlayItem Flags
+const (
+	PIFIsMultiAngle         uint16 = 0x10
+	PIFConnectionCondition  uint16 = 0x0F
+	SPIFIsMultiClipEntries  byte   = 0x01
+	SPIFConnectionCondition byte   = 0x1E
+)
+
+// SubPath Flags
+const (
+	SPFIsRepeat uint16 = 0x01
+)
+
+// Connection conditions
+const (
+	CCNotSeamless       = 1
+	CCSeamless          = 5
+	CCSeamlessNoOverlap = 6
 )
 
 // Angle Flags
 const (
-	AFIsDifferentAudios = 1 << (iota + 7)
-	AFIsSeamlessAngleChange
+	AFIsDifferentAudios     = 0x02
+	AFIsSeamlessAngleChange = 0x01
+)
+
+// MarkType
+const (
+	MarkEntry     = 0x01
+	MarkLinkPoint = 0x02
+)
+
+// NoOffsetSequence is the offset sequence ID of graphics without a 3D offset
+const NoOffsetSequence = 0xFF
+
+// Extension data entry IDs, ID1 in the high 16 bits and ID2 in the low 16 bits
+const (
+	ExtensionPiPMetadata    = 0x00010001
+	ExtensionSTNTableSS     = 0x00020001
+	ExtensionSubPaths       = 0x00020002
+	ExtensionStaticMetadata = 0x00030005
 )
 
+// CodingType is the stream_coding_type of a stream
+type CodingType byte
+
 // VideoType
 const (
-	VTMPEG1Video = 0x01
-	VTMPEG2Video = 0x02
-	VTVC1        = 0xea
-	VTH264       = 0x1b
+	VTMPEG1Video CodingType = 0x01
+	VTMPEG2Video CodingType = 0x02
+	VTVC1        CodingType = 0xea
+	VTH264       CodingType = 0x1b
+	VTMVC        CodingType = 0x20
+	VTHEVC       CodingType = 0x24
 )
 
 // AudioType
 const (
-	ATMPEG1Audio  = 0x03
-	ATMPEG2Audio  = 0x04
-	ATLPCM        = 0x80
-	ATAC3         = 0x81
-	ATDTS         = 0x82
-	ATTRUEHD      = 0x83
-	ATAC3Plus     = 0x84
-	ATDTSHD       = 0x85
-	ATDTSHDMaster = 0x86
+	ATMPEG1Audio  CodingType = 0x03
+	ATMPEG2Audio  CodingType = 0x04
+	ATLPCM        CodingType = 0x80
+	ATAC3         CodingType = 0x81
+	ATDTS         CodingType = 0x82
+	ATTRUEHD      CodingType = 0x83
+	ATAC3Plus     CodingType = 0x84
+	ATDTSHD       CodingType = 0x85
+	ATDTSHDMaster CodingType = 0x86
+
+	ATAC3PlusSecondary CodingType = 0xa1 // secondary audio only
+	ATDTSHDSecondary   CodingType = 0xa2 // secondary audio only
 )
 
 // OtherType
 const (
-	PresentationGraphics = 0x90
-	InteractiveGraphics  = 0x91
-	TextSubtitle         = 0x92
+	PresentationGraphics CodingType = 0x90
+	InteractiveGraphics  CodingType = 0x91
+	TextSubtitle         CodingType = 0x92
 )
 
+// VideoFormat is the video_format of a video stream
+type VideoFormat byte
+
 // VideoFormat
 const (
-	VFReserved = iota
+	VFReserved VideoFormat = iota
 	VF480I
 	VF576I
 	VF480P
@@ -81,49 +142,86 @@ const (
 	VF720P
 	VF1080P
 	VF576P
+	VF2160P
+)
+
+// DynamicRange is the dynamic_range_type of an HEVC video stream
+type DynamicRange byte
+
+// DynamicRange
+const (
+	DRSDR         DynamicRange = 0
+	DRHDR10       DynamicRange = 1
+	DRDolbyVision DynamicRange = 2
+)
+
+// ColorSpace is the color_space of an HEVC video stream
+type ColorSpace byte
+
+// ColorSpace
+const (
+	CSReserved ColorSpace = 0
+	CSBT709    ColorSpace = 1
+	CSBT2020   ColorSpace = 2
 )
 
+// FrameRate is the frame_rate of a video stream
+type FrameRate byte
+
 // FrameRate
 const (
-	FRReserved = iota
-	FR23976    // 23.976
-	FR24       // 24
-	FR25       // 25
-	FR2997     // 29.97
-	FR50       // 50
-	FR5994     // 59.94
+	FRReserved FrameRate = iota
+	FR23976              // 23.976
+	FR24                 // 24
+	FR25                 // 25
+	FR2997               // 29.97
+	_
+	FR50   // 50
+	FR5994 // 59.94
 )
 
+// AspectRatio is the aspect_ratio of a video stream
+type AspectRatio byte
+
 // AspectRatio
 const (
-	ARReserved = 0
-	AR43       = 2 //4:3
-	AR169      = 3 //16:9
+	ARReserved AspectRatio = 0
+	AR43       AspectRatio = 2 //4:3
+	AR169      AspectRatio = 3 //16:9
 )
 
+// AudioPresentation is the audio_presentation_type of an audio stream
+type AudioPresentation byte
+
 // AudioPresentation
 const (
-	APReserved = 0
-	APMono     = 1
-	APDualMono = 2
-	APStereo   = 3
-	APMulti    = 6
-	APCombo    = 12
+	APReserved AudioPresentation = 0
+	APMono     AudioPresentation = 1
+	APDualMono AudioPresentation = 2
+	APStereo   AudioPresentation = 3
+	APMulti    AudioPresentation = 6
+	APCombo    AudioPresentation = 12
 )
 
+// SampleRate is the sampling_frequency of an audio stream
+type SampleRate byte
+
 // SampleRate
 const (
-	SRReserved = 0
-	SR48       = 1
-	SR96       = 4
-	SR192      = 5
-	SR48192    = 12 // 48/192
-	SR4896     = 14 // 48/96
+	SRReserved SampleRate = 0
+	SR48       SampleRate = 1
+	SR96       SampleRate = 4
+	SR192      SampleRate = 5
+	SR48192    SampleRate = 12 // 48/192
+	SR4896     SampleRate = 14 // 48/96
 )
 
+// CharacterCode is the character_code of a text subtitle stream
+type CharacterCode byte
+
 // CharacterCode
 const (
-	ReservedCharacterCode = iota
+	ReservedCharacterCode CharacterCode = iota
 	UTF8
 	UTF16
 	ShiftJIS // Japanese
@@ -131,7 +229,7 @@ const (
 	GB18030  // Chinese
 	GB2312   // Chinese
 	BIG5     // Chinese
-) // Chinese
+)
 
 // MPLS is a struct representing an MPLS file
 type MPLS struct {
@@ -143,8 +241,15 @@ type MPLS struct {
 	AppInfoPlaylist    AppInfoPlaylist
 	Playlist           Playlist
 	MarkPlaylist       PlaylistMark
+	ExtensionData      ExtensionData
 	SegmentMap         []string
-	Duration           int64
+	Chapters           []Chapter
+	Duration           time.Duration
+	Warnings           []Warning
+
+	reserved [20]byte
+	// padding before the Playlist, PlaylistMark and ExtensionData sections and after the last section
+	padding [4][]byte
 }
 
 // AppInfoPlaylist sucks
@@ -154,6 +259,9 @@ type AppInfoPlaylist struct {
 	PlaybackCount uint16
 	PlaylistFlags uint16
 	UOMask        uint64
+
+	reserved byte
+	extra    []byte
 }
 
 type Playlist struct {
@@ -162,23 +270,28 @@ type Playlist struct {
 	SubPathCount  uint16
 	PlayItems     []PlayItem
 	SubPaths      []SubPath
+
+	reserved [2]byte
+	extra    []byte
 }
 
 // PlayItem contains information about a an item in the playlist
 type PlayItem struct {
 	Len              uint16
 	Flags            uint16 // multiangle/connection condition
-	InTime           int
-	OutTime          int
+	InTime           Ticks
+	OutTime          Ticks
 	UOMask           uint64
 	RandomAccessFlag byte
-	AngleCount       byte
+	AngleCount       byte // number of angles including Clpi, only set when the PlayItem is multi-angle
 	AngleFlags       byte
 	StillMode        byte
 	StillTime        uint16
 	Clpi             CLPI
-	Angles           []CLPI
+	Angles           []CLPI // angles 2 and up, angle 1 is Clpi
 	StreamTable      STNTable
+
+	extra []byte
 }
 
 // STNTable STream Number Table
@@ -188,15 +301,71 @@ type STNTable struct {
 	PrimaryAudioStreamCount   byte
 	PrimaryPGStreamCount      byte
 	PrimaryIGStreamCount      byte
-	SecondaryVideoStreamCount byte
 	SecondaryAudioStreamCount byte
+	SecondaryVideoStreamCount byte
 	PIPPGStreamCount          byte
+	DolbyVisionStreamCount    byte // only in UHD playlists, version 0300
 	PrimaryVideoStreams       []PrimaryStream
 	PrimaryAudioStreams       []PrimaryStream
 	PrimaryPGStreams          []PrimaryStream
+	PIPPGStreams              []PrimaryStream // numbered after PrimaryPGStreams
 	PrimaryIGStreams          []PrimaryStream
 	SecondaryAudioStreams     []SecondaryAudioStream
 	SecondaryVideoStreams     []SecondaryVideoStream
+	DolbyVisionStreams        []PrimaryStream // Dolby Vision enhancement layers of UHD playlists
+
+	// Extra holds the bytes between the last stream and the end of the table given by Len,
+	// newer tables may carry data here. It is written back unchanged
+	Extra []byte
+
+	// SS is the STN_table_SS of 3D playlists, it is stored in the ExtensionSTNTableSS extension entry
+	SS STNTableSS
+
+	reserved [7]byte
+}
+
+// STNTableSS is the STN_table_SS of a PlayItem, the stereoscopic streams of a 3D playlist.
+// Its lists follow the order of the PrimaryVideoStreams, PrimaryPGStreams and PrimaryIGStreams of the STNTable
+type STNTableSS struct {
+	Len                    uint16
+	FixedOffsetDuringPopUp bool
+	DependentViewStreams   []DependentViewStream
+	PGStreams              []GraphicsStreamSS
+	IGStreams              []GraphicsStreamSS
+
+	// Extra holds the bytes between the last stream and the end of the table given by Len,
+	// the secondary audio and video streams of the table are not decoded. It is written back unchanged
+	Extra []byte
+
+	flags uint16
+}
+
+// DependentViewStream is the MVC dependent view of a primary video stream
+type DependentViewStream struct {
+	PrimaryStream
+	OffsetSequenceCount byte // number of 3D offset sequences carried in the dependent view
+
+	flags uint16
+}
+
+// GraphicsStreamSS holds the 3D offset sequence IDs and stereoscopic streams of a PG or IG stream.
+// An offset sequence ID of NoOffsetSequence means no offset is applied
+type GraphicsStreamSS struct {
+	OffsetSequenceID        byte // offset sequence used when the stream is shown as 2D plus offset
+	DialogRegionOffsetValid bool // PG only
+	IsSS                    bool // Left and Right are the left and right eye streams
+	IsTopAS                 bool // PG only, Top is a subtitle stream for the top of the screen
+	IsBottomAS              bool // PG only, Bottom is a subtitle stream for the bottom of the screen
+	Left                    StreamEntry
+	Right                   StreamEntry
+	SSOffsetSequenceID      byte
+	Top                     StreamEntry
+	TopOffsetSequenceID     byte
+	Bottom                  StreamEntry
+	BottomOffsetSequenceID  byte
+
+	flags    byte
+	reserved [3]byte
 }
 
 // PrimaryStream holds a stream entry and attributes
@@ -209,6 +378,8 @@ type PrimaryStream struct {
 type SecondaryStream struct {
 	RefrenceEntryCount byte
 	StreamIDs          []byte
+
+	reserved [2]byte
 }
 
 // SecondaryAudioStream holds a primary stream and a secondary stream
@@ -232,16 +403,26 @@ type StreamEntry struct {
 	PID       uint16
 	SubPathID byte
 	SubClipID byte
+
+	raw []byte
 }
 
 // StreamAttributes holds metadata about the data stream
 type StreamAttributes struct {
 	Len           byte
-	Encoding      byte
-	Format        byte
-	Rate          byte
-	CharacterCode byte
+	Encoding      CodingType
+	Format        byte // VideoFormat or AudioPresentation
+	Rate          byte // FrameRate or SampleRate
+	CharacterCode CharacterCode
 	Language      string
+
+	// HEVC video only
+	DynamicRange DynamicRange
+	ColorSpace   ColorSpace
+	CRFlag       bool
+	HDRPlusFlag  bool // HDR10+
+
+	raw []byte
 }
 
 // CLPI contains the fiLename and the codec ID
@@ -251,43 +432,141 @@ type CLPI struct {
 	STCID    byte
 }
 
+// SubPath is a path played alongside the main path of PlayItems
 type SubPath struct {
 	Len           int
-	Type          byte
+	Type          SubPathType
 	PlayItemCount byte
-	Flags         uint16
+	Flags         uint16 // is_repeat_SubPath
 	SubPlayItems  []SubPlayItem
+
+	reserved [2]byte
+	extra    []byte
 }
 
 // SubPlayItem contains information about a PlayItem in the subpath
 type SubPlayItem struct {
 	Len              uint16
-	Flags            byte // multiangle/connection condition
-	StartOfPlayitem  uint32
-	InTime           int
-	OutTime          int
+	Flags            byte  // multiangle/connection condition
+	StartOfPlayitem  Ticks // time in the PlayItem PlayItemID at which the SubPlayItem starts
+	InTime           Ticks
+	OutTime          Ticks
 	UOMask           uint64
 	RandomAccessFlag byte
 	AngleCount       byte
 	AngleFlags       byte
 	StillMode        byte
 	StillTime        uint16
-	PlayItemID       uint16
+	PlayItemID       uint16 // the PlayItem StartOfPlayitem is in
 	Clpi             CLPI
 	Angles           []CLPI
 	StreamTable      STNTable
+
+	reserved [3]byte
+	extra    []byte
 }
 
+// PlaylistMark holds the marks of the playlist
 type PlaylistMark struct {
-	Len       uint64
+	Len       int
 	MarkCount uint16
 	Marks     []Mark
+
+	extra []byte
 }
 
+// Mark is a single entry in the PlaylistMark section.
+// Time is a 45 kHz timestamp on the timeline of the referenced PlayItem
 type Mark struct {
 	Type        byte
 	PlayItemRef uint16
-	Time        uint32
+	Time        Ticks
 	PID         uint16
-	Duration    uint32
+	Duration    Ticks
+
+	reserved byte
+}
+
+// Chapter is an entry mark placed on the playlist timeline.
+// Start is relative to the start of the playlist
+type Chapter struct {
+	Start       Ticks
+	Duration    Ticks
+	PlayItemRef uint16
+	Mark        Mark
+}
+
+// ExtensionData holds the entries of the ExtensionData block.
+// Entries keep their raw payload, known entries are also decoded
+type ExtensionData struct {
+	Len            int
+	DataBlockStart int
+	EntryCount     byte
+	Entries        []ExtensionEntry
+	SubPaths       []SubPath
+	StaticMetadata []StaticMetadata
+	PiPMetadata    []PiPMetadata
+
+	reserved [3]byte
+	order    []int // indexes of Entries in the order of their data
+	padding  []byte
+	extra    []byte
+}
+
+// ExtensionEntry is a single entry of the ExtensionData block.
+// Start is relative to the start of the ExtensionData block.
+// Data is written back unchanged except for the ExtensionSubPaths entry
+// which is encoded from ExtensionData.SubPaths, the ExtensionSTNTableSS entry
+// which is encoded from the STNTable.SS of every PlayItem and the ExtensionPiPMetadata entry
+// which is encoded from ExtensionData.PiPMetadata.
+// The decoded StaticMetadata is not written back
+type ExtensionEntry struct {
+	ID1   uint16
+	ID2   uint16
+	Start int
+	Len   int
+	Data  []byte
+
+	padding []byte
+	extra   []byte
+}
+
+// PiPMetadata is a block of the PiP metadata extension entry.
+// It gives the position and scale of a secondary video stream over time
+type PiPMetadata struct {
+	PlayItemRef       uint16 // PlayItem whose STNTable has the secondary video stream
+	SecondaryVideoRef byte   // index of the stream in SecondaryVideoStreams
+	TimelineType      PiPTimelineType
+	LumaKey           bool // pixels with a luma up to UpperLimitLumaKey are transparent
+	TrickPlay         bool // the PiP window is shown during trick play
+	UpperLimitLumaKey byte
+	DataAddress       int // start of Entries, relative to the start of the extension entry, recomputed by MarshalBinary
+	Entries           []PiPEntry
+
+	flags    uint16
+	reserved [4]byte
+	padding  []byte
+}
+
+// PiPEntry places the PiP window from Time on
+type PiPEntry struct {
+	Time  Ticks  // relative to the timeline given by the TimelineType
+	X     uint16 // horizontal position of the top left corner in pixels
+	Y     uint16 // vertical position of the top left corner in pixels
+	Scale PiPScale
+
+	reserved byte
+}
+
+// StaticMetadata holds the HDR static metadata of a UHD playlist
+type StaticMetadata struct {
+	DynamicRangeType             byte
+	DisplayPrimariesX            [3]uint16
+	DisplayPrimariesY            [3]uint16
+	WhitePointX                  uint16
+	WhitePointY                  uint16
+	MaxDisplayMasteringLuminance uint16
+	MinDisplayMasteringLuminance uint16
+	MaxCLL                       uint16
+	MaxFALL                      uint16
 }
@@ -0,0 +1,232 @@
+package mpls
+
+import "fmt"
+
+var codingTypeNames = map[CodingType]string{
+	VTMPEG1Video:         "MPEG-1 Video",
+	VTMPEG2Video:         "MPEG-2 Video",
+	VTVC1:                "VC-1",
+	VTH264:               "H.264/AVC",
+	VTMVC:                "H.264/MVC",
+	VTHEVC:               "H.265/HEVC",
+	ATMPEG1Audio:         "MPEG-1 Audio",
+	ATMPEG2Audio:         "MPEG-2 Audio",
+	ATLPCM:               "LPCM",
+	ATAC3:                "Dolby Digital",
+	ATDTS:                "DTS",
+	ATTRUEHD:             "Dolby TrueHD",
+	ATAC3Plus:            "Dolby Digital Plus",
+	ATDTSHD:              "DTS-HD High Resolution Audio",
+	ATDTSHDMaster:        "DTS-HD Master Audio",
+	ATAC3PlusSecondary:   "Dolby Digital Plus (secondary)",
+	ATDTSHDSecondary:     "DTS-HD (secondary)",
+	PresentationGraphics: "Presentation Graphics",
+	InteractiveGraphics:  "Interactive Graphics",
+	TextSubtitle:         "Text Subtitle",
+}
+
+func (ct CodingType) String() string {
+	if name, ok := codingTypeNames[ct]; ok {
+		return name
+	}
+	return fmt.Sprintf("CodingType(0x%02X)", byte(ct))
+}
+
+// IsVideo reports whether ct is a video coding type
+func (ct CodingType) IsVideo() bool {
+	switch ct {
+	case VTMPEG1Video, VTMPEG2Video, VTVC1, VTH264, VTMVC, VTHEVC:
+		return true
+	}
+	return false
+}
+
+// IsAudio reports whether ct is an audio coding type
+func (ct CodingType) IsAudio() bool {
+	switch ct {
+	case ATMPEG1Audio, ATMPEG2Audio, ATLPCM, ATAC3, ATDTS, ATTRUEHD, ATAC3Plus, ATDTSHD, ATDTSHDMaster, ATAC3PlusSecondary, ATDTSHDSecondary:
+		return true
+	}
+	return false
+}
+
+var videoFormatNames = map[VideoFormat]string{
+	VF480I:  "480i",
+	VF576I:  "576i",
+	VF480P:  "480p",
+	VF1080I: "1080i",
+	VF720P:  "720p",
+	VF1080P: "1080p",
+	VF576P:  "576p",
+	VF2160P: "2160p",
+}
+
+func (vf VideoFormat) String() string {
+	if name, ok := videoFormatNames[vf]; ok {
+		return name
+	}
+	return fmt.Sprintf("VideoFormat(%d)", byte(vf))
+}
+
+var dynamicRangeNames = map[DynamicRange]string{
+	DRSDR:         "SDR",
+	DRHDR10:       "HDR10",
+	DRDolbyVision: "Dolby Vision",
+}
+
+func (dr DynamicRange) String() string {
+	if name, ok := dynamicRangeNames[dr]; ok {
+		return name
+	}
+	return fmt.Sprintf("DynamicRange(%d)", byte(dr))
+}
+
+var colorSpaceNames = map[ColorSpace]string{
+	CSBT709:  "BT.709",
+	CSBT2020: "BT.2020",
+}
+
+func (cs ColorSpace) String() string {
+	if name, ok := colorSpaceNames[cs]; ok {
+		return name
+	}
+	return fmt.Sprintf("ColorSpace(%d)", byte(cs))
+}
+
+var frameRateNames = map[FrameRate]string{
+	FR23976: "23.976",
+	FR24:    "24",
+	FR25:    "25",
+	FR2997:  "29.97",
+	FR50:    "50",
+	FR5994:  "59.94",
+}
+
+func (fr FrameRate) String() string {
+	if name, ok := frameRateNames[fr]; ok {
+		return name
+	}
+	return fmt.Sprintf("FrameRate(%d)", byte(fr))
+}
+
+var aspectRatioNames = map[AspectRatio]string{
+	AR43:  "4:3",
+	AR169: "16:9",
+}
+
+func (ar AspectRatio) String() string {
+	if name, ok := aspectRatioNames[ar]; ok {
+		return name
+	}
+	return fmt.Sprintf("AspectRatio(%d)", byte(ar))
+}
+
+var audioPresentationNames = map[AudioPresentation]string{
+	APMono:     "Mono",
+	APDualMono: "Dual Mono",
+	APStereo:   "Stereo",
+	APMulti:    "Multi-channel",
+	APCombo:    "Stereo + Multi-channel",
+}
+
+func (ap AudioPresentation) String() string {
+	if name, ok := audioPresentationNames[ap]; ok {
+		return name
+	}
+	return fmt.Sprintf("AudioPresentation(%d)", byte(ap))
+}
+
+var sampleRateNames = map[SampleRate]string{
+	SR48:    "48 kHz",
+	SR96:    "96 kHz",
+	SR192:   "192 kHz",
+	SR48192: "48/192 kHz",
+	SR4896:  "48/96 kHz",
+}
+
+func (sr SampleRate) String() string {
+	if name, ok := sampleRateNames[sr]; ok {
+		return name
+	}
+	return fmt.Sprintf("SampleRate(%d)", byte(sr))
+}
+
+var characterCodeNames = map[CharacterCode]string{
+	UTF8:     "UTF-8",
+	UTF16:    "UTF-16BE",
+	ShiftJIS: "Shift-JIS",
+	KSC5601:  "KSC 5601",
+	GB18030:  "GB18030",
+	GB2312:   "GB2312",
+	BIG5:     "Big5",
+}
+
+func (cc CharacterCode) String() string {
+	if name, ok := characterCodeNames[cc]; ok {
+		return name
+	}
+	return fmt.Sprintf("CharacterCode(%d)", byte(cc))
+}
+
+// VideoFormat returns Format as a VideoFormat, VFReserved if the stream is not video
+func (sa StreamAttributes) VideoFormat() VideoFormat {
+	if !sa.Encoding.IsVideo() {
+		return VFReserved
+	}
+	return VideoFormat(sa.Format)
+}
+
+// FrameRate returns Rate as a FrameRate, FRReserved if the stream is not video
+func (sa StreamAttributes) FrameRate() FrameRate {
+	if !sa.Encoding.IsVideo() {
+		return FRReserved
+	}
+	return FrameRate(sa.Rate)
+}
+
+// AudioPresentation returns Format as an AudioPresentation, APReserved if the stream is not audio
+func (sa StreamAttributes) AudioPresentation() AudioPresentation {
+	if !sa.Encoding.IsAudio() {
+		return APReserved
+	}
+	return AudioPresentation(sa.Format)
+}
+
+// SampleRate returns Rate as a SampleRate, SRReserved if the stream is not audio
+func (sa StreamAttributes) SampleRate() SampleRate {
+	if !sa.Encoding.IsAudio() {
+		return SRReserved
+	}
+	return SampleRate(sa.Rate)
+}
+
+// LanguageName returns the English name of the stream's language, see LanguageName
+func (sa StreamAttributes) LanguageName() string {
+	return LanguageName(sa.Language)
+}
+
+// String describes the stream e.g. "H.264/AVC 1080p 23.976" or "DTS-HD Master Audio Multi-channel 48 kHz English"
+func (sa StreamAttributes) String() string {
+	s := sa.Encoding.String()
+	switch {
+	case sa.Encoding == VTHEVC:
+		s += " " + sa.VideoFormat().String() + " " + sa.FrameRate().String() + " " + sa.DynamicRange.String() + " " + sa.ColorSpace.String()
+		if sa.HDRPlusFlag {
+			s += " HDR10+"
+		}
+	case sa.Encoding.IsVideo():
+		s += " " + sa.VideoFormat().String() + " " + sa.FrameRate().String()
+	case sa.Encoding.IsAudio():
+		s += " " + sa.AudioPresentation().String() + " " + sa.SampleRate().String()
+	case sa.Encoding == TextSubtitle:
+		s += " " + sa.CharacterCode.String()
+	}
+	if sa.Language != "" {
+		if name := sa.LanguageName(); name != "" {
+			s += " " + name
+		} else {
+			s += " " + sa.Language
+		}
+	}
+	return s
+}
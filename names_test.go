@@ -0,0 +1,75 @@
+package mpls
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		value fmt.Stringer
+		want  string
+	}{
+		{VTH264, "H.264/AVC"},
+		{VTHEVC, "H.265/HEVC"},
+		{ATDTSHDMaster, "DTS-HD Master Audio"},
+		{TextSubtitle, "Text Subtitle"},
+		{CodingType(0x42), "CodingType(0x42)"},
+		{VF1080P, "1080p"},
+		{VF2160P, "2160p"},
+		{VideoFormat(15), "VideoFormat(15)"},
+		{FR23976, "23.976"},
+		{FR5994, "59.94"},
+		{FRReserved, "FrameRate(0)"},
+		{DRDolbyVision, "Dolby Vision"},
+		{DynamicRange(9), "DynamicRange(9)"},
+		{CSBT2020, "BT.2020"},
+		{CSReserved, "ColorSpace(0)"},
+		{AR169, "16:9"},
+		{ARReserved, "AspectRatio(0)"},
+		{APMulti, "Multi-channel"},
+		{APCombo, "Stereo + Multi-channel"},
+		{AudioPresentation(4), "AudioPresentation(4)"},
+		{SR48192, "48/192 kHz"},
+		{SampleRate(2), "SampleRate(2)"},
+		{UTF16, "UTF-16BE"},
+		{CharacterCode(0xFF), "CharacterCode(255)"},
+	}
+	for _, test := range tests {
+		if s := test.value.String(); s != test.want {
+			t.Errorf("%T %v = %q, want %q", test.value, test.value, s, test.want)
+		}
+	}
+}
+
+func TestStreamAttributesString(t *testing.T) {
+	tests := []struct {
+		sa   StreamAttributes
+		want string
+	}{
+		{StreamAttributes{Encoding: VTH264, Format: byte(VF1080P), Rate: byte(FR23976)}, "H.264/AVC 1080p 23.976"},
+		{
+			StreamAttributes{Encoding: VTHEVC, Format: byte(VF2160P), Rate: byte(FR24), DynamicRange: DRHDR10, ColorSpace: CSBT2020, HDRPlusFlag: true},
+			"H.265/HEVC 2160p 24 HDR10 BT.2020 HDR10+",
+		},
+		{StreamAttributes{Encoding: ATDTSHDMaster, Format: byte(APMulti), Rate: byte(SR48), Language: "eng"}, "DTS-HD Master Audio Multi-channel 48 kHz English"},
+		{StreamAttributes{Encoding: TextSubtitle, CharacterCode: UTF8, Language: "jpn"}, "Text Subtitle UTF-8 Japanese"},
+		// an unknown language is shown as its code
+		{StreamAttributes{Encoding: PresentationGraphics, Language: "xx1"}, "Presentation Graphics xx1"},
+	}
+	for _, test := range tests {
+		if s := test.sa.String(); s != test.want {
+			t.Errorf("%+v = %q, want %q", test.sa, s, test.want)
+		}
+	}
+
+	// the video accessors do not reinterpret the fields of an audio stream and the other way round
+	audio := StreamAttributes{Encoding: ATAC3, Format: byte(APStereo), Rate: byte(SR48)}
+	if audio.VideoFormat() != VFReserved || audio.FrameRate() != FRReserved {
+		t.Errorf("audio stream has video format %v and frame rate %v", audio.VideoFormat(), audio.FrameRate())
+	}
+	video := StreamAttributes{Encoding: VTVC1, Format: byte(VF1080I), Rate: byte(FR2997)}
+	if video.AudioPresentation() != APReserved || video.SampleRate() != SRReserved {
+		t.Errorf("video stream has audio presentation %v and sample rate %v", video.AudioPresentation(), video.SampleRate())
+	}
+}
@@ -6,44 +6,49 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
+	"sort"
+
+	"timmy.narnian.us/mpls/internal/bdparse"
 )
 
+// errReader is the shared reader with the state specific to playlists
 type errReader struct {
-	RS  *bytes.Reader
-	err error
+	bdparse.Reader
+	version string // version of the file, some fields depend on it
 }
 
-func (er *errReader) Read(p []byte) (n int, err error) {
-	if er.err != nil {
-		return 0, er.err
-	}
-
-	n, er.err = er.RS.Read(p)
-	if n != len(p) {
-		er.err = fmt.Errorf("%s", "Invalid read")
-	}
-
-	return n, er.err
+// warn records a warning of kind for the current section at the current position
+func (er *errReader) warn(kind WarningKind, expected int64, format string, a ...interface{}) {
+	er.Warn(int(kind), expected, format, a...)
 }
 
-func (er *errReader) Seek(offset int64, whence int) (int64, error) {
-	if er.err != nil {
-		return 0, er.err
+// warnings returns the warnings recorded so far
+func (er *errReader) warnings() []Warning {
+	var warnings []Warning
+	for _, w := range er.Warnings {
+		warnings = append(warnings, Warning{
+			Kind:     WarningKind(w.Kind),
+			Section:  w.Section,
+			Expected: w.Expected,
+			Actual:   w.Actual,
+			Message:  w.Message,
+		})
 	}
-
-	var n64 int64
-	n64, er.err = er.RS.Seek(offset, whence)
-
-	return n64, er.err
+	return warnings
 }
 
-// Parse parses an MPLS file into an MPLS struct
+// Parse parses an MPLS file into an MPLS struct.
+// A reader that is an io.ReadSeeker is parsed from its current position with ParseReadSeeker,
+// other readers are read whole first
 func Parse(reader io.Reader) (mpls MPLS, err error) {
 	var (
 		file []byte
 	)
 
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		return ParseReadSeeker(rs, SectionAll)
+	}
+
 	file, err = ioutil.ReadAll(reader)
 	if err != nil {
 		return MPLS{}, err
@@ -53,66 +58,169 @@ func Parse(reader io.Reader) (mpls MPLS, err error) {
 	return mpls, err
 }
 
-// Parse reads MPLS data from an io.ReadSeeker
+// Parse reads MPLS data from the bytes of a whole playlist file
 func (mpls *MPLS) Parse(file []byte) error {
+	return mpls.parse(bytes.NewReader(file), SectionAll)
+}
+
+// parse reads the header and the selected sections from rs.
+// Only when every section is selected are the warnings about section starts and the padding between sections kept
+func (mpls *MPLS) parse(rs bdparse.Source, sections Section) error {
 	var (
-		buf   [10]byte
-		n     int
-		err   error
-		start int64
+		err error
+		all = sections&SectionAll == SectionAll
 	)
 
 	reader := &errReader{
-		RS:  bytes.NewReader(file),
-		err: nil,
+		Reader: bdparse.Reader{
+			RS:     rs,
+			Format: "mpls",
+		},
+	}
+	defer func() {
+		mpls.Warnings = reader.warnings()
+	}()
+
+	err = mpls.parseHeader(reader)
+	if err != nil {
+		return err
+	}
+
+	for _, section := range []struct {
+		section Section
+		name    string
+		start   int
+		warn    bool    // warn if the previous section did not end at start
+		padding *[]byte // bytes between the previous section and start
+		parse   func(reader *errReader) error
+	}{
+		{SectionAppInfo, "AppInfoPlaylist", headerLen, false, nil, mpls.AppInfoPlaylist.parse},
+		{SectionPlaylist, "Playlist", mpls.PlaylistStart, true, &mpls.padding[0], mpls.Playlist.parse},
+		{SectionMarks, "MarkPlaylist", mpls.PlaylistMarkStart, true, &mpls.padding[1], mpls.MarkPlaylist.parse},
+		{SectionExtensionData, "ExtensionData", mpls.ExtensionDataStart, false, &mpls.padding[2], func(reader *errReader) error {
+			return mpls.ExtensionData.parse(reader, mpls.Playlist.PlayItems)
+		}},
+	} {
+		if sections&section.section == 0 || (section.section == SectionExtensionData && section.start == 0) {
+			continue
+		}
+
+		reader.Enter(section.name)
+		if all && section.warn && reader.Pos() != int64(section.start) {
+			reader.warn(WarnSectionStart, int64(section.start), "")
+		}
+		if all && section.padding != nil {
+			*section.padding = reader.Gap(int64(section.start))
+		}
+		_, _ = reader.Seek(int64(section.start), io.SeekStart)
+		err = section.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
 	}
 
+	if all {
+		mpls.padding[3] = reader.Gap(reader.RS.Size())
+		if reader.Err != nil {
+			return reader.Err
+		}
+	}
+
+	if sections&SectionPlaylist != 0 {
+		mpls.derive()
+	}
+	return reader.Err
+}
+
+// parseHeader reads the type indicator, version and section start addresses from an *errReader
+func (mpls *MPLS) parseHeader(reader *errReader) error {
+	var (
+		buf [10]byte
+		n   int
+		err error
+	)
+
 	n, err = reader.Read(buf[:8])
 	if err != nil || n != 8 {
 		return err
 	}
 	str := string(buf[:8])
 	if str[:4] != "MPLS" {
-		return fmt.Errorf("not an mpls file it must start with 'MPLS' it started with '%s'", str[:4])
-	}
-	if str[4:8] != "0200" {
-		fmt.Fprintf(os.Stderr, "warning: mpls may not work it is version %s\n", str[4:8])
+		return reader.Fail(0, fmt.Errorf("%w: not an mpls file it must start with 'MPLS' it started with '%s'", ErrBadMagic, str[:4]))
 	}
-
 	mpls.FileType = str[:4]
 	mpls.Version = str[4:8]
+	reader.version = mpls.Version
+
+	switch mpls.Version {
+	case "0100", "0200", "0300":
+	default:
+		reader.warn(WarnVersion, 4, "mpls may not work it is version %s", mpls.Version)
+	}
 
-	mpls.PlaylistStart, _ = readInt32(reader, buf[:])
+	mpls.PlaylistStart, _ = bdparse.ReadInt32(reader, buf[:])
 
-	mpls.PlaylistMarkStart, _ = readInt32(reader, buf[:])
+	mpls.PlaylistMarkStart, _ = bdparse.ReadInt32(reader, buf[:])
 
-	mpls.ExtensionDataStart, _ = readInt32(reader, buf[:])
+	mpls.ExtensionDataStart, _ = bdparse.ReadInt32(reader, buf[:])
 
-	_, _ = reader.Seek(20, io.SeekCurrent)
+	_, _ = reader.Read(mpls.reserved[:])
 
-	_ = mpls.AppInfoPlaylist.parse(reader)
+	return reader.Err
+}
 
-	start, _ = reader.Seek(0, io.SeekCurrent)
-	if start != int64(mpls.PlaylistStart) {
-		fmt.Fprintf(os.Stderr, "Playlist doesn't start at the right place. Current position is %d position should be %d\n", start, int64(mpls.PlaylistStart))
+// derive fills in SegmentMap, Duration and Chapters from the parsed sections
+func (mpls *MPLS) derive() {
+	mpls.SegmentMap = make([]string, 0, len(mpls.Playlist.PlayItems))
+	for _, playitem := range mpls.Playlist.PlayItems {
+		mpls.SegmentMap = append(mpls.SegmentMap, playitem.Clpi.ClipFile)
 	}
+	mpls.Duration = ticksDuration(mpls.Length())
+	mpls.Chapters = mpls.chapters()
+}
 
-	_, _ = reader.Seek(int64(mpls.PlaylistStart), io.SeekStart)
-	_ = mpls.Playlist.parse(reader)
+// chapters places the entry marks on the playlist timeline in order.
+// A mark past the end of its PlayItem is placed at the end of it
+func (mpls *MPLS) chapters() []Chapter {
+	var (
+		offsets  = mpls.playItemOffsets()
+		chapters []Chapter
+	)
 
-	start, _ = reader.Seek(0, io.SeekCurrent)
-	if start != int64(mpls.PlaylistMarkStart) {
-		fmt.Fprintf(os.Stderr, "Mark Playlist doesn't start at the right place. Current position is %d position should be %d\n", start, int64(mpls.PlaylistStart))
+	for _, mark := range mpls.MarkPlaylist.Marks {
+		if mark.Type != MarkEntry || int(mark.PlayItemRef) >= len(mpls.Playlist.PlayItems) {
+			continue
+		}
+		start := offsets[mark.PlayItemRef]
+		if playitem := mpls.Playlist.PlayItems[mark.PlayItemRef]; mark.Time > playitem.InTime {
+			start += mark.Time - playitem.InTime
+		}
+		if end := offsets[mark.PlayItemRef+1]; start > end {
+			start = end
+		}
+		chapters = append(chapters, Chapter{
+			Start:       start,
+			PlayItemRef: mark.PlayItemRef,
+			Mark:        mark,
+		})
 	}
 
-	// _ = mpls.MarkPlaylist.parse(reader)
-	mpls.SegmentMap = make([]string, 0, len(mpls.Playlist.PlayItems))
-	for _, playitem := range mpls.Playlist.PlayItems {
-		mpls.SegmentMap = append(mpls.SegmentMap, playitem.Clpi.ClipFile)
-		mpls.Duration += int64(playitem.OutTime - playitem.InTime)
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Start < chapters[j].Start
+	})
+
+	for i := range chapters {
+		end := offsets[len(offsets)-1]
+		if i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		}
+		if end > chapters[i].Start {
+			chapters[i].Duration = end - chapters[i].Start
+		}
 	}
-	mpls.Duration = mpls.Duration / 4500
-	return reader.err
+
+	return chapters
 }
 
 // parse reads AppInfoPlaylist data from an *errReader
@@ -120,29 +228,24 @@ func (aip *AppInfoPlaylist) parse(reader *errReader) error {
 	var (
 		buf   [10]byte
 		start int64
-		end   int64
 	)
 
-	aip.Len, _ = readInt32(reader, buf[:])
+	aip.Len, _ = bdparse.ReadInt32(reader, buf[:])
 
 	start, _ = reader.Seek(0, io.SeekCurrent)
 
 	_, _ = reader.Read(buf[:2])
 
+	aip.reserved = buf[0]
 	aip.PlaybackType = buf[1]
 
-	aip.PlaybackCount, _ = readUInt16(reader, buf[:])
+	aip.PlaybackCount, _ = bdparse.ReadUInt16(reader, buf[:])
 
-	aip.UOMask, _ = readUInt64(reader, buf[:])
+	aip.UOMask, _ = bdparse.ReadUInt64(reader, buf[:])
 
-	aip.PlaylistFlags, _ = readUInt16(reader, buf[:])
+	aip.PlaylistFlags, _ = bdparse.ReadUInt16(reader, buf[:])
 
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(aip.Len)) {
-		fmt.Fprintf(os.Stderr, "App Info Playlist is not aligned. App Info Playlist started at %d current position is %d position should be %d\n", start, end, start+int64(aip.Len))
-	}
-
-	return reader.err
+	return reader.Align(start, int64(aip.Len), &aip.extra)
 }
 
 // parse reads Playlist data from an *errReader
@@ -151,43 +254,51 @@ func (p *Playlist) parse(reader *errReader) error {
 		buf   [10]byte
 		err   error
 		start int64
-		end   int64
 	)
 
-	p.Len, _ = readInt32(reader, buf[:])
+	p.Len, _ = bdparse.ReadInt32(reader, buf[:])
 
 	start, _ = reader.Seek(0, io.SeekCurrent)
 
-	_, _ = reader.Seek(2, io.SeekCurrent)
+	_, _ = reader.Read(p.reserved[:])
+
+	p.PlayItemCount, _ = bdparse.ReadUInt16(reader, buf[:])
 
-	p.PlayItemCount, _ = readUInt16(reader, buf[:])
+	p.SubPathCount, _ = bdparse.ReadUInt16(reader, buf[:])
 
-	p.SubPathCount, _ = readUInt16(reader, buf[:])
+	err = reader.Count(int(p.PlayItemCount), 2, start+int64(p.Len))
+	if err != nil {
+		return err
+	}
 
 	for i := 0; i < int(p.PlayItemCount); i++ {
 		var item PlayItem
+		reader.Enter("PlayItems[%d]", i)
 		err = item.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
 		p.PlayItems = append(p.PlayItems, item)
 	}
 
+	err = reader.Count(int(p.SubPathCount), 4, start+int64(p.Len))
+	if err != nil {
+		return err
+	}
+
 	for i := 0; i < int(p.SubPathCount); i++ {
 		var item SubPath
+		reader.Enter("SubPaths[%d]", i)
 		err = item.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
 		p.SubPaths = append(p.SubPaths, item)
 	}
 
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(p.Len)) {
-		fmt.Fprintf(os.Stderr, "Playlist is not aligned. Playlist started at %d current position is %d position should be %d\n", start, end, start+int64(p.Len))
-	}
-
-	return reader.err
+	return reader.Align(start, int64(p.Len), &p.extra)
 }
 
 // parse reads PlayItem data from an *errReader
@@ -196,10 +307,9 @@ func (pi *PlayItem) parse(reader *errReader) error {
 		buf   [10]byte
 		err   error
 		start int64
-		end   int64
 	)
 
-	pi.Len, _ = readUInt16(reader, buf[:])
+	pi.Len, _ = bdparse.ReadUInt16(reader, buf[:])
 
 	start, _ = reader.Seek(0, io.SeekCurrent)
 
@@ -207,22 +317,22 @@ func (pi *PlayItem) parse(reader *errReader) error {
 
 	str := string(buf[:9])
 	if str[5:9] != "M2TS" {
-		fmt.Fprintf(os.Stderr, "warning: this playlist may be faulty it has a play item that is '%s' not 'M2TS'", str[4:8])
+		reader.warn(WarnUnknownCodecID, start+5, "this playlist may be faulty it has a play item that is '%s' not 'M2TS'", str[5:9])
 	}
 	pi.Clpi.ClipFile = str[:5]
 	pi.Clpi.ClipID = str[5:9]
 
-	pi.Flags, _ = readUInt16(reader, buf[:])
+	pi.Flags, _ = bdparse.ReadUInt16(reader, buf[:])
 
 	_, _ = reader.Read(buf[:1])
 
 	pi.Clpi.STCID = buf[0]
 
-	pi.InTime, _ = readInt32(reader, buf[:])
+	pi.InTime, _ = readTicks(reader, buf[:])
 
-	pi.OutTime, _ = readInt32(reader, buf[:])
+	pi.OutTime, _ = readTicks(reader, buf[:])
 
-	pi.UOMask, _ = readUInt64(reader, buf[:])
+	pi.UOMask, _ = bdparse.ReadUInt64(reader, buf[:])
 
 	_, _ = reader.Read(buf[:2])
 
@@ -230,18 +340,29 @@ func (pi *PlayItem) parse(reader *errReader) error {
 
 	pi.StillMode = buf[1]
 
-	pi.StillTime, _ = readUInt16(reader, buf[:])
+	pi.StillTime, _ = bdparse.ReadUInt16(reader, buf[:])
 
-	if pi.Flags&1<<3 == 1 {
+	if pi.Flags&PIFIsMultiAngle != 0 {
 		_, _ = reader.Read(buf[:2])
 
 		pi.AngleCount = buf[0]
 
 		pi.AngleFlags = buf[1]
 
-		for i := 0; i < int(pi.AngleCount); i++ {
+		// the first angle is Clpi, the count includes it
+		err = reader.Count(int(pi.AngleCount)-1, 10, start+int64(pi.Len))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < int(pi.AngleCount)-1; i++ {
 			var angle CLPI
-			_ = angle.parse(reader)
+			reader.Enter("Angles[%d]", i)
+			err = angle.parse(reader)
+			reader.Leave()
+			if err != nil {
+				return err
+			}
 			_, err = reader.Read(buf[:1])
 			if err != nil {
 				return err
@@ -251,29 +372,28 @@ func (pi *PlayItem) parse(reader *errReader) error {
 		}
 	}
 
-	_ = pi.StreamTable.parse(reader)
-
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(pi.Len)) {
-		fmt.Fprintf(os.Stderr, "playitem is not aligned. Playitem started at %d current position is %d position should be %d\n", start, end, start+int64(pi.Len))
+	reader.Enter("StreamTable")
+	err = pi.StreamTable.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
 	}
 
-	return reader.err
+	return reader.Align(start, int64(pi.Len), &pi.extra)
 }
 
-// parse reads angle data from an *errReader
+// parse reads the clip name and codec identifier from an *errReader
 func (clpi *CLPI) parse(reader *errReader) error {
 	var (
 		buf [10]byte
 	)
-	_, _ = reader.Read(buf[:])
+	_, _ = reader.Read(buf[:9])
 
 	str := string(buf[:9])
 	clpi.ClipFile = str[:5]
 	clpi.ClipID = str[5:9]
 
-	// clpi.STCID = buf[9]
-	return reader.err
+	return reader.Err
 }
 
 // parse reads PrimaryStream data from an *errReader
@@ -282,14 +402,14 @@ func (stnt *STNTable) parse(reader *errReader) error {
 		buf   [10]byte
 		err   error
 		start int64
-		end   int64
 	)
-	stnt.Len, _ = readUInt16(reader, buf[:])
+	stnt.Len, _ = bdparse.ReadUInt16(reader, buf[:])
 
 	start, _ = reader.Seek(0, io.SeekCurrent)
 
 	_, _ = reader.Read(buf[:9])
 
+	copy(stnt.reserved[:2], buf[:2])
 	stnt.PrimaryVideoStreamCount = buf[2]
 	stnt.PrimaryAudioStreamCount = buf[3]
 	stnt.PrimaryPGStreamCount = buf[4]
@@ -298,11 +418,26 @@ func (stnt *STNTable) parse(reader *errReader) error {
 	stnt.SecondaryVideoStreamCount = buf[7]
 	stnt.PIPPGStreamCount = buf[8]
 
-	_, _ = reader.Seek(5, io.SeekCurrent)
+	_, _ = reader.Read(stnt.reserved[2:])
+
+	// UHD playlists count the Dolby Vision streams in the first reserved byte
+	if reader.version == "0300" {
+		stnt.DolbyVisionStreamCount, stnt.reserved[2] = stnt.reserved[2], 0
+	}
+
+	err = reader.Count(int(stnt.PrimaryVideoStreamCount)+int(stnt.PrimaryAudioStreamCount)+
+		int(stnt.PrimaryPGStreamCount)+int(stnt.PrimaryIGStreamCount)+
+		int(stnt.SecondaryAudioStreamCount)+int(stnt.SecondaryVideoStreamCount)+
+		int(stnt.PIPPGStreamCount)+int(stnt.DolbyVisionStreamCount), 2, start+int64(stnt.Len))
+	if err != nil {
+		return err
+	}
 
 	for i := 0; i < int(stnt.PrimaryVideoStreamCount); i++ {
 		var stream PrimaryStream
+		reader.Enter("PrimaryVideoStreams[%d]", i)
 		err = stream.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
@@ -311,7 +446,9 @@ func (stnt *STNTable) parse(reader *errReader) error {
 
 	for i := 0; i < int(stnt.PrimaryAudioStreamCount); i++ {
 		var stream PrimaryStream
+		reader.Enter("PrimaryAudioStreams[%d]", i)
 		err = stream.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
@@ -320,16 +457,31 @@ func (stnt *STNTable) parse(reader *errReader) error {
 
 	for i := 0; i < int(stnt.PrimaryPGStreamCount); i++ {
 		var stream PrimaryStream
+		reader.Enter("PrimaryPGStreams[%d]", i)
 		err = stream.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
 		stnt.PrimaryPGStreams = append(stnt.PrimaryPGStreams, stream)
 	}
 
+	for i := 0; i < int(stnt.PIPPGStreamCount); i++ {
+		var stream PrimaryStream
+		reader.Enter("PIPPGStreams[%d]", i)
+		err = stream.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		stnt.PIPPGStreams = append(stnt.PIPPGStreams, stream)
+	}
+
 	for i := 0; i < int(stnt.PrimaryIGStreamCount); i++ {
 		var stream PrimaryStream
+		reader.Enter("PrimaryIGStreams[%d]", i)
 		err = stream.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
@@ -338,7 +490,9 @@ func (stnt *STNTable) parse(reader *errReader) error {
 
 	for i := 0; i < int(stnt.SecondaryAudioStreamCount); i++ {
 		var stream SecondaryAudioStream
+		reader.Enter("SecondaryAudioStreams[%d]", i)
 		err = stream.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
@@ -347,19 +501,27 @@ func (stnt *STNTable) parse(reader *errReader) error {
 
 	for i := 0; i < int(stnt.SecondaryVideoStreamCount); i++ {
 		var stream SecondaryVideoStream
+		reader.Enter("SecondaryVideoStreams[%d]", i)
 		err = stream.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
 		stnt.SecondaryVideoStreams = append(stnt.SecondaryVideoStreams, stream)
 	}
 
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(stnt.Len)) {
-		fmt.Fprintf(os.Stderr, "STN Table is not aligned. STN Table started at %d current position is %d position should be %d\n", start, end, start+int64(stnt.Len))
+	for i := 0; i < int(stnt.DolbyVisionStreamCount); i++ {
+		var stream PrimaryStream
+		reader.Enter("DolbyVisionStreams[%d]", i)
+		err = stream.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		stnt.DolbyVisionStreams = append(stnt.DolbyVisionStreams, stream)
 	}
 
-	return reader.err
+	return reader.Align(start, int64(stnt.Len), &stnt.Extra)
 }
 
 // parse reads SecondaryStream data from an *errReader
@@ -370,56 +532,234 @@ func (ss *SecondaryStream) parse(reader *errReader) error {
 
 	_, _ = reader.Read(buf[:2])
 	ss.RefrenceEntryCount = buf[0]
+	ss.reserved[0] = buf[1]
 	ss.StreamIDs = make([]byte, ss.RefrenceEntryCount)
 	_, _ = reader.Read(ss.StreamIDs)
 	if ss.RefrenceEntryCount%2 != 0 {
-		_, _ = reader.Seek(1, io.SeekCurrent)
+		_, _ = reader.Read(ss.reserved[1:])
 	}
-	return reader.err
+	return reader.Err
 }
 
 // parse reads SecondaryAudioStream data from an *errReader
 func (sas *SecondaryAudioStream) parse(reader *errReader) error {
-	_ = sas.PrimaryStream.parse(reader)
-	_ = sas.ExtraAttributes.parse(reader)
+	var (
+		err error
+	)
 
-	return reader.err
+	reader.Enter("PrimaryStream")
+	err = sas.PrimaryStream.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+	reader.Enter("ExtraAttributes")
+	err = sas.ExtraAttributes.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+
+	return reader.Err
 }
 
 // parse reads SecondaryVideoStream data from an *errReader
 func (svs *SecondaryVideoStream) parse(reader *errReader) error {
-	_ = svs.PrimaryStream.parse(reader)
-	_ = svs.ExtraAttributes.parse(reader)
-	_ = svs.PGStream.parse(reader)
+	var (
+		err error
+	)
+
+	reader.Enter("PrimaryStream")
+	err = svs.PrimaryStream.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+	reader.Enter("ExtraAttributes")
+	err = svs.ExtraAttributes.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
+	reader.Enter("PGStream")
+	err = svs.PGStream.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
 
-	return reader.err
+	return reader.Err
+}
+
+// parse reads STNTableSS data for the streams of stnt from an *errReader
+func (ss *STNTableSS) parse(reader *errReader, stnt *STNTable) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+	)
+	ss.Len, _ = bdparse.ReadUInt16(reader, buf[:])
+
+	start, _ = reader.Seek(0, io.SeekCurrent)
+
+	ss.flags, _ = bdparse.ReadUInt16(reader, buf[:])
+	ss.FixedOffsetDuringPopUp = ss.flags&0x8000 != 0
+
+	for i := range stnt.PrimaryVideoStreams {
+		var stream DependentViewStream
+		reader.Enter("DependentViewStreams[%d]", i)
+		err = stream.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		ss.DependentViewStreams = append(ss.DependentViewStreams, stream)
+	}
+
+	for i := range stnt.PrimaryPGStreams {
+		var stream GraphicsStreamSS
+		reader.Enter("PGStreams[%d]", i)
+		err = stream.parse(reader, true)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		ss.PGStreams = append(ss.PGStreams, stream)
+	}
+
+	for i := range stnt.PrimaryIGStreams {
+		var stream GraphicsStreamSS
+		reader.Enter("IGStreams[%d]", i)
+		err = stream.parse(reader, false)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		ss.IGStreams = append(ss.IGStreams, stream)
+	}
+
+	return reader.Align(start, int64(ss.Len), &ss.Extra)
+}
+
+// parse reads DependentViewStream data from an *errReader
+func (dvs *DependentViewStream) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+		err error
+	)
+
+	err = dvs.PrimaryStream.parse(reader)
+	if err != nil {
+		return err
+	}
+
+	dvs.flags, _ = bdparse.ReadUInt16(reader, buf[:])
+	dvs.OffsetSequenceCount = byte(dvs.flags & 0x3F)
+
+	return reader.Err
+}
+
+// parse reads GraphicsStreamSS data from an *errReader, pg selects the PG layout over the IG layout
+func (gs *GraphicsStreamSS) parse(reader *errReader, pg bool) error {
+	var (
+		buf [10]byte
+		err error
+	)
+
+	_, _ = reader.Read(buf[:2])
+	gs.OffsetSequenceID = buf[0]
+	gs.flags = buf[1]
+	if pg {
+		gs.DialogRegionOffsetValid = gs.flags&0x08 != 0
+		gs.IsSS = gs.flags&0x04 != 0
+		gs.IsTopAS = gs.flags&0x02 != 0
+		gs.IsBottomAS = gs.flags&0x01 != 0
+	} else {
+		gs.IsSS = gs.flags&0x01 != 0
+	}
+
+	if gs.IsSS {
+		reader.Enter("Left")
+		err = gs.Left.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		reader.Enter("Right")
+		err = gs.Right.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		_, _ = reader.Read(buf[:2])
+		gs.reserved[0] = buf[0]
+		gs.SSOffsetSequenceID = buf[1]
+	}
+
+	if gs.IsTopAS {
+		reader.Enter("Top")
+		err = gs.Top.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		_, _ = reader.Read(buf[:2])
+		gs.reserved[1] = buf[0]
+		gs.TopOffsetSequenceID = buf[1]
+	}
+
+	if gs.IsBottomAS {
+		reader.Enter("Bottom")
+		err = gs.Bottom.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		_, _ = reader.Read(buf[:2])
+		gs.reserved[2] = buf[0]
+		gs.BottomOffsetSequenceID = buf[1]
+	}
+
+	return reader.Err
 }
 
 // parse reads Stream data from an *errReader
 func (ps *PrimaryStream) parse(reader *errReader) error {
+	var (
+		err error
+	)
 
-	_ = ps.StreamEntry.parse(reader)
+	reader.Enter("StreamEntry")
+	err = ps.StreamEntry.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
 
-	_ = ps.StreamAttributes.parse(reader)
+	reader.Enter("StreamAttributes")
+	err = ps.StreamAttributes.parse(reader)
+	reader.Leave()
+	if err != nil {
+		return err
+	}
 
-	return reader.err
+	return reader.Err
 }
 
 // parse reads Stream data from an *errReader
 func (se *StreamEntry) parse(reader *errReader) error {
 	var (
-		buf   [10]byte
-		start int64
-		end   int64
+		buf [10]byte
 	)
 
 	_, _ = reader.Read(buf[:1])
 
 	se.Len = buf[0]
 
-	start, _ = reader.Seek(0, io.SeekCurrent)
+	se.raw = make([]byte, se.Len)
+	_, _ = reader.Read(se.raw)
+	copy(buf[:], se.raw)
 
-	_, _ = reader.Read(buf[:9])
 	se.Type = buf[0]
 	switch se.Type {
 	case 1:
@@ -433,12 +773,7 @@ func (se *StreamEntry) parse(reader *errReader) error {
 		se.PID = binary.BigEndian.Uint16(buf[2:4])
 	}
 
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(se.Len)) {
-		fmt.Fprintf(os.Stderr, "Stream Entry is not aligned. Stream Entry started at %d current position is %d position should be %d\n", start, end, start+int64(se.Len))
-	}
-
-	return reader.err
+	return reader.Err
 }
 
 // parse reads Stream data from an *errReader
@@ -446,55 +781,385 @@ func (sa *StreamAttributes) parse(reader *errReader) error {
 	var (
 		buf   [10]byte
 		start int64
-		end   int64
 	)
 
 	_, _ = reader.Read(buf[:1])
 
 	sa.Len = buf[0]
 
+	start = reader.Pos()
+	sa.raw = make([]byte, sa.Len)
+	_, _ = reader.Read(sa.raw)
+	copy(buf[:], sa.raw)
+
+	sa.Encoding = CodingType(buf[0])
+
+	switch sa.Encoding {
+	case VTMPEG1Video, VTMPEG2Video, VTVC1, VTH264, VTMVC:
+		sa.Format = buf[1] & 0xf0 >> 4
+		sa.Rate = buf[1] & 0x0F
+
+	case VTHEVC:
+		sa.Format = buf[1] & 0xf0 >> 4
+		sa.Rate = buf[1] & 0x0F
+		sa.DynamicRange = DynamicRange(buf[2] & 0xf0 >> 4)
+		sa.ColorSpace = ColorSpace(buf[2] & 0x0F)
+		sa.CRFlag = buf[3]&0x80 != 0
+		sa.HDRPlusFlag = buf[3]&0x40 != 0
+
+	case ATMPEG1Audio, ATMPEG2Audio, ATLPCM, ATAC3, ATDTS, ATTRUEHD, ATAC3Plus, ATDTSHD, ATDTSHDMaster, ATAC3PlusSecondary, ATDTSHDSecondary:
+		sa.Format = buf[1] & 0xf0 >> 4
+		sa.Rate = buf[1] & 0x0F
+		sa.Language = string(buf[2:5])
+
+	case PresentationGraphics, InteractiveGraphics:
+		sa.Language = string(buf[1:4])
+
+	case TextSubtitle:
+		sa.CharacterCode = CharacterCode(buf[1])
+		sa.Language = string(buf[2:5])
+	default:
+		reader.warn(WarnUnknownEncoding, start, "unrecognized encoding: '%02X'", byte(sa.Encoding))
+	}
+
+	return reader.Err
+}
+
+// parse reads PlaylistMark data from an *errReader
+func (plm *PlaylistMark) parse(reader *errReader) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+	)
+
+	plm.Len, _ = bdparse.ReadInt32(reader, buf[:])
+
 	start, _ = reader.Seek(0, io.SeekCurrent)
 
-	_, _ = reader.Read(buf[:1])
+	plm.MarkCount, _ = bdparse.ReadUInt16(reader, buf[:])
 
-	sa.Encoding = buf[0]
+	err = reader.Count(int(plm.MarkCount), 14, start+int64(plm.Len))
+	if err != nil {
+		return err
+	}
 
-	switch sa.Encoding {
-	case VTMPEG1Video, VTMPEG2Video, VTVC1, VTH264:
-		_, _ = reader.Read(buf[:1])
+	for i := 0; i < int(plm.MarkCount); i++ {
+		var mark Mark
+		reader.Enter("Marks[%d]", i)
+		err = mark.parse(reader)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+		plm.Marks = append(plm.Marks, mark)
+	}
 
-		sa.Format = buf[0] & 0xf0 >> 4
-		sa.Rate = buf[0] & 0x0F
-		_, _ = reader.Seek(3, io.SeekCurrent)
+	return reader.Align(start, int64(plm.Len), &plm.extra)
+}
 
-	case ATMPEG1Audio, ATMPEG2Audio, ATLPCM, ATAC3, ATDTS, ATTRUEHD, ATAC3Plus, ATDTSHD, ATDTSHDMaster:
-		_, _ = reader.Read(buf[:4])
+// parse reads Mark data from an *errReader
+func (m *Mark) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+	)
 
-		sa.Format = buf[0] & 0xf0 >> 4
-		sa.Rate = buf[0] & 0x0F
-		sa.Language = string(buf[1:4])
+	_, _ = reader.Read(buf[:2])
 
-	case PresentationGraphics, InteractiveGraphics:
-		_, _ = reader.Read(buf[:3])
+	m.reserved = buf[0]
+	m.Type = buf[1]
 
-		sa.Language = string(buf[:3])
-		_, _ = reader.Seek(1, io.SeekCurrent)
+	m.PlayItemRef, _ = bdparse.ReadUInt16(reader, buf[:])
 
-	case TextSubtitle:
+	m.Time, _ = readTicks(reader, buf[:])
+
+	m.PID, _ = bdparse.ReadUInt16(reader, buf[:])
+
+	m.Duration, _ = readTicks(reader, buf[:])
+
+	return reader.Err
+}
+
+// parse reads ExtensionData data from an *errReader
+func (ed *ExtensionData) parse(reader *errReader, playitems []PlayItem) error {
+	var (
+		buf   [10]byte
+		err   error
+		start int64
+		end   int64
+	)
+
+	start, _ = reader.Seek(0, io.SeekCurrent)
+
+	ed.Len, _ = bdparse.ReadInt32(reader, buf[:])
+	if ed.Len == 0 {
+		return reader.Err
+	}
+
+	ed.DataBlockStart, _ = bdparse.ReadInt32(reader, buf[:])
+
+	_, _ = reader.Read(buf[:4])
+
+	copy(ed.reserved[:], buf[:3])
+	ed.EntryCount = buf[3]
+
+	err = reader.Count(int(ed.EntryCount), 12, start+4+int64(ed.Len))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(ed.EntryCount); i++ {
+		var entry ExtensionEntry
+		entry.ID1, _ = bdparse.ReadUInt16(reader, buf[:])
+		entry.ID2, _ = bdparse.ReadUInt16(reader, buf[:])
+		entry.Start, _ = bdparse.ReadInt32(reader, buf[:])
+		entry.Len, _ = bdparse.ReadInt32(reader, buf[:])
+		ed.Entries = append(ed.Entries, entry)
+	}
+
+	ed.padding = reader.Gap(start + int64(ed.DataBlockStart))
+	end = start + int64(ed.DataBlockStart)
+
+	// the data of the entries is read in the order it is in the file, which need not be the order of the table
+	ed.order = make([]int, len(ed.Entries))
+	for i := range ed.order {
+		ed.order[i] = i
+	}
+	sort.SliceStable(ed.order, func(i, j int) bool {
+		return ed.Entries[ed.order[i]].Start < ed.Entries[ed.order[j]].Start
+	})
+
+	for _, i := range ed.order {
+		entry := &ed.Entries[i]
+		if entry.Len == 0 {
+			continue
+		}
+		if start+int64(entry.Start) >= end {
+			_, _ = reader.Seek(end, io.SeekStart)
+			entry.padding = reader.Gap(start + int64(entry.Start))
+		}
+		_, _ = reader.Seek(start+int64(entry.Start), io.SeekStart)
+		entry.Data = reader.Bytes(int64(entry.Len))
+		if reader.Err != nil {
+			return reader.Err
+		}
+		end = reader.Pos()
+		reader.Enter("Entries[%d]", i)
+		err = ed.decode(reader, entry, start+int64(entry.Start), playitems)
+		reader.Leave()
+		if err != nil {
+			return err
+		}
+	}
+
+	ed.extra = reader.Gap(start + 4 + int64(ed.Len))
+
+	return reader.Err
+}
+
+// decode parses the payload of a known extension entry located at start.
+// STN_table_SS entries are stored in the STNTable of playitems.
+// Warnings are reported to parent
+func (ed *ExtensionData) decode(parent *errReader, entry *ExtensionEntry, start int64, playitems []PlayItem) error {
+	var (
+		buf [10]byte
+		err error
+	)
+
+	reader := &errReader{
+		Reader: bdparse.Reader{
+			RS:     bytes.NewReader(entry.Data),
+			Base:   parent.Base + start,
+			Format: parent.Format,
+			Path:   append([]string(nil), parent.Path...),
+		},
+		version: parent.version,
+	}
+	defer func() {
+		parent.Warnings = append(parent.Warnings, reader.Warnings...)
+	}()
+
+	switch entry.ID() {
+	case ExtensionSubPaths:
+		var count uint16
+		_, _ = bdparse.ReadInt32(reader, buf[:])
+		count, _ = bdparse.ReadUInt16(reader, buf[:])
+		err = reader.Count(int(count), 4, int64(entry.Len))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(count); i++ {
+			var item SubPath
+			reader.Enter("SubPaths[%d]", i)
+			err = item.parse(reader)
+			reader.Leave()
+			if err != nil {
+				return err
+			}
+			ed.SubPaths = append(ed.SubPaths, item)
+		}
+		entry.extra = reader.Gap(int64(entry.Len))
+
+	case ExtensionSTNTableSS:
+		for i := range playitems {
+			reader.Enter("STNTableSS[%d]", i)
+			err = playitems[i].StreamTable.SS.parse(reader, &playitems[i].StreamTable)
+			reader.Leave()
+			if err != nil {
+				return err
+			}
+		}
+		entry.extra = reader.Gap(int64(entry.Len))
+
+	case ExtensionPiPMetadata:
+		var (
+			count uint16
+			end   int64
+		)
+		_, _ = bdparse.ReadInt32(reader, buf[:])
+		count, _ = bdparse.ReadUInt16(reader, buf[:])
+		err = reader.Count(int(count), 14, int64(entry.Len))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(count); i++ {
+			var metadata PiPMetadata
+			reader.Enter("PiPMetadata[%d]", i)
+			err = metadata.parse(reader)
+			reader.Leave()
+			if err != nil {
+				return err
+			}
+			ed.PiPMetadata = append(ed.PiPMetadata, metadata)
+		}
+		end = reader.Pos()
+		for i := range ed.PiPMetadata {
+			reader.Enter("PiPMetadata[%d]", i)
+			err = ed.PiPMetadata[i].parseEntries(reader, end)
+			reader.Leave()
+			if err != nil {
+				return err
+			}
+			if reader.Pos() > end {
+				end = reader.Pos()
+			}
+		}
+		_, _ = reader.Seek(end, io.SeekStart)
+		entry.extra = reader.Gap(int64(entry.Len))
+
+	case ExtensionStaticMetadata:
+		_, _ = bdparse.ReadInt32(reader, buf[:])
 		_, _ = reader.Read(buf[:4])
+		count := buf[0]
+		err = reader.Count(int(count), 28, int64(entry.Len))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(count); i++ {
+			var metadata StaticMetadata
+			reader.Enter("StaticMetadata[%d]", i)
+			err = metadata.parse(reader)
+			reader.Leave()
+			if err != nil {
+				return err
+			}
+			ed.StaticMetadata = append(ed.StaticMetadata, metadata)
+		}
+	}
 
-		sa.CharacterCode = buf[0]
-		sa.Language = string(buf[1:4])
-	default:
-		fmt.Fprintf(os.Stderr, "warning: unrecognized encoding: '%02X'\n", sa.Encoding)
+	return reader.Err
+}
+
+// ID returns ID1 and ID2 combined for comparison with the Extension constants
+func (entry ExtensionEntry) ID() uint32 {
+	return uint32(entry.ID1)<<16 | uint32(entry.ID2)
+}
+
+// parse reads StaticMetadata data from an *errReader
+func (sm *StaticMetadata) parse(reader *errReader) error {
+	var (
+		buf [10]byte
+	)
+
+	_, _ = reader.Read(buf[:4])
+
+	sm.DynamicRangeType = buf[0] >> 4
+
+	for i := range sm.DisplayPrimariesX {
+		sm.DisplayPrimariesX[i], _ = bdparse.ReadUInt16(reader, buf[:])
+		sm.DisplayPrimariesY[i], _ = bdparse.ReadUInt16(reader, buf[:])
 	}
 
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(sa.Len)) {
-		fmt.Fprintf(os.Stderr, "Stream Attributes is not aligned. Stream Attributes started at %d current position is %d position should be %d\n", start, end, start+int64(sa.Len))
+	sm.WhitePointX, _ = bdparse.ReadUInt16(reader, buf[:])
+	sm.WhitePointY, _ = bdparse.ReadUInt16(reader, buf[:])
+	sm.MaxDisplayMasteringLuminance, _ = bdparse.ReadUInt16(reader, buf[:])
+	sm.MinDisplayMasteringLuminance, _ = bdparse.ReadUInt16(reader, buf[:])
+	sm.MaxCLL, _ = bdparse.ReadUInt16(reader, buf[:])
+	sm.MaxFALL, _ = bdparse.ReadUInt16(reader, buf[:])
+
+	return reader.Err
+}
+
+// parse reads a PiPMetadata block and the entries at its DataAddress from an *errReader
+func (pm *PiPMetadata) parse(reader *errReader) error {
+	var (
+		buf   [10]byte
+		flags uint16
+	)
+
+	pm.PlayItemRef, _ = bdparse.ReadUInt16(reader, buf[:])
+	_, _ = reader.Read(buf[:2])
+	pm.SecondaryVideoRef = buf[0]
+	pm.reserved[0] = buf[1]
+
+	flags, _ = bdparse.ReadUInt16(reader, buf[:])
+	pm.TimelineType = PiPTimelineType(flags >> 12)
+	pm.LumaKey = flags&0x0800 != 0
+	pm.TrickPlay = flags&0x0400 != 0
+	pm.flags = flags & 0x03FF
+
+	_, _ = reader.Read(buf[:4])
+	pm.reserved[1] = buf[0]
+	pm.UpperLimitLumaKey = buf[1]
+	pm.reserved[2], pm.reserved[3] = buf[2], buf[3]
+
+	pm.DataAddress, _ = bdparse.ReadInt32(reader, buf[:])
+
+	return reader.Err
+}
+
+// parseEntries reads the entries at DataAddress.
+// Entries starting at or after end, the end of the data read so far, keep the bytes before them as padding
+func (pm *PiPMetadata) parseEntries(reader *errReader, end int64) error {
+	var (
+		buf   [10]byte
+		err   error
+		count uint16
+	)
+
+	if int64(pm.DataAddress) >= end {
+		_, _ = reader.Seek(end, io.SeekStart)
+		pm.padding = reader.Gap(int64(pm.DataAddress))
 	}
+	_, _ = reader.Seek(int64(pm.DataAddress), io.SeekStart)
 
-	return reader.err
+	count, _ = bdparse.ReadUInt16(reader, buf[:])
+	err = reader.Count(int(count), 8, reader.RS.Size())
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		var entry PiPEntry
+		entry.Time, _ = readTicks(reader, buf[:])
+		_, _ = reader.Read(buf[:4])
+		entry.X = uint16(buf[0])<<4 | uint16(buf[1]>>4)
+		entry.Y = uint16(buf[1]&0x0F)<<8 | uint16(buf[2])
+		entry.Scale = PiPScale(buf[3] >> 4)
+		entry.reserved = buf[3] & 0x0F
+		pm.Entries = append(pm.Entries, entry)
+	}
+
+	return reader.Err
 }
 
 func (sp *SubPath) parse(reader *errReader) error {
@@ -502,35 +1167,38 @@ func (sp *SubPath) parse(reader *errReader) error {
 		buf   [10]byte
 		err   error
 		start int64
-		end   int64
 	)
 
-	sp.Len, _ = readInt32(reader, buf[:])
+	sp.Len, _ = bdparse.ReadInt32(reader, buf[:])
 
 	start, _ = reader.Seek(0, io.SeekCurrent)
 
 	_, _ = reader.Read(buf[:2])
-	sp.Type = buf[1]
-	sp.Flags, _ = readUInt16(reader, buf[:])
+	sp.reserved[0] = buf[0]
+	sp.Type = SubPathType(buf[1])
+	sp.Flags, _ = bdparse.ReadUInt16(reader, buf[:])
 
 	_, _ = reader.Read(buf[:2])
+	sp.reserved[1] = buf[0]
 	sp.PlayItemCount = buf[1]
 
+	err = reader.Count(int(sp.PlayItemCount), 2, start+int64(sp.Len))
+	if err != nil {
+		return err
+	}
+
 	for i := 0; i < int(sp.PlayItemCount); i++ {
 		var item SubPlayItem
+		reader.Enter("SubPlayItems[%d]", i)
 		err = item.parse(reader)
+		reader.Leave()
 		if err != nil {
 			return err
 		}
 		sp.SubPlayItems = append(sp.SubPlayItems, item)
 	}
 
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(sp.Len)) {
-		fmt.Fprintf(os.Stderr, "Subpath is not aligned. Subpath started at %d current position is %d position should be %d\n", start, end, start+int64(sp.Len))
-	}
-
-	return reader.err
+	return reader.Align(start, int64(sp.Len), &sp.extra)
 }
 
 func (spi *SubPlayItem) parse(reader *errReader) error {
@@ -538,35 +1206,49 @@ func (spi *SubPlayItem) parse(reader *errReader) error {
 		buf   [10]byte
 		err   error
 		start int64
-		end   int64
 	)
 
-	spi.Len, _ = readUInt16(reader, buf[:])
+	spi.Len, _ = bdparse.ReadUInt16(reader, buf[:])
 
 	start, _ = reader.Seek(0, io.SeekCurrent)
 
-	_ = spi.Clpi.parse(reader)
+	err = spi.Clpi.parse(reader)
+	if err != nil {
+		return err
+	}
 
-	_, _ = reader.Read(buf[:4])
+	_, _ = reader.Read(buf[:5])
 
-	spi.Flags = buf[2]
-	spi.Clpi.STCID = buf[3]
+	copy(spi.reserved[:], buf[:3])
+	spi.Flags = buf[3]
+	spi.Clpi.STCID = buf[4]
 
-	spi.InTime, _ = readInt32(reader, buf[:])
-	spi.OutTime, _ = readInt32(reader, buf[:])
+	spi.InTime, _ = readTicks(reader, buf[:])
+	spi.OutTime, _ = readTicks(reader, buf[:])
 
-	spi.PlayItemID, _ = readUInt16(reader, buf[:])
-	spi.StartOfPlayitem, _ = readUInt32(reader, buf[:])
+	spi.PlayItemID, _ = bdparse.ReadUInt16(reader, buf[:])
+	spi.StartOfPlayitem, _ = readTicks(reader, buf[:])
 
-	if spi.Flags&1<<3 == 1 {
+	if spi.Flags&SPIFIsMultiClipEntries != 0 {
 		_, _ = reader.Read(buf[:2])
 
 		spi.AngleCount = buf[0]
 		spi.AngleFlags = buf[1]
 
-		for i := 0; i < int(spi.AngleCount); i++ {
+		// the first clip is Clpi, the count includes it
+		err = reader.Count(int(spi.AngleCount)-1, 10, start+int64(spi.Len))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < int(spi.AngleCount)-1; i++ {
 			var angle CLPI
-			_ = angle.parse(reader)
+			reader.Enter("Angles[%d]", i)
+			err = angle.parse(reader)
+			reader.Leave()
+			if err != nil {
+				return err
+			}
 			_, err = reader.Read(buf[:1])
 			if err != nil {
 				return err
@@ -576,39 +1258,10 @@ func (spi *SubPlayItem) parse(reader *errReader) error {
 		}
 	}
 
-	end, _ = reader.Seek(0, io.SeekCurrent)
-	if end != (start + int64(spi.Len)) {
-		fmt.Fprintf(os.Stderr, "Subplayitem is not aligned. Subplayitem started at %d current position is %d position should be %d\n", start, end, start+int64(spi.Len))
-	}
-
-	return reader.err
-}
-
-func readUInt16(reader io.Reader, buf []byte) (uint16, error) {
-	n, err := reader.Read(buf[:2])
-	if err != nil || n != 2 {
-		return 0, err
-	}
-	return binary.BigEndian.Uint16(buf[:2]), nil
-}
-
-func readInt32(reader io.Reader, buf []byte) (int, error) {
-	n, err := readUInt32(reader, buf)
-	return int(n), err
-}
-
-func readUInt32(reader io.Reader, buf []byte) (uint32, error) {
-	n, err := reader.Read(buf[:4])
-	if err != nil || n != 4 {
-		return 0, err
-	}
-	return binary.BigEndian.Uint32(buf[:4]), nil
+	return reader.Align(start, int64(spi.Len), &spi.extra)
 }
 
-func readUInt64(reader io.Reader, buf []byte) (uint64, error) {
-	n, err := reader.Read(buf[:8])
-	if err != nil || n != 8 {
-		return 0, err
-	}
-	return binary.BigEndian.Uint64(buf[:8]), nil
+func readTicks(reader io.Reader, buf []byte) (Ticks, error) {
+	n, err := bdparse.ReadUInt32(reader, buf)
+	return Ticks(n), err
 }
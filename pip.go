@@ -0,0 +1,111 @@
+package mpls
+
+import "fmt"
+
+// PiPTimelineType is the pip_timeline_type of a PiPMetadata block, it gives the timeline of the entry times
+type PiPTimelineType byte
+
+// PiP timeline types
+const (
+	PiPTimelineSynchronous          PiPTimelineType = 1 // times are on the timeline of the PlayItem
+	PiPTimelineAsynchronousSubPath  PiPTimelineType = 2 // times are on the timeline of the SubPlayItem playing the secondary video
+	PiPTimelineAsynchronousPlayItem PiPTimelineType = 3 // times are relative to the start of the secondary video on the PlayItem timeline
+)
+
+func (tt PiPTimelineType) String() string {
+	switch tt {
+	case PiPTimelineSynchronous:
+		return "Synchronous"
+	case PiPTimelineAsynchronousSubPath:
+		return "Asynchronous SubPath"
+	case PiPTimelineAsynchronousPlayItem:
+		return "Asynchronous PlayItem"
+	}
+	return fmt.Sprintf("PiPTimelineType(%d)", byte(tt))
+}
+
+// PiPScale is the scaling of the PiP window
+type PiPScale byte
+
+// PiP scales
+const (
+	PiPScaleNone       PiPScale = 1
+	PiPScaleHalf       PiPScale = 2
+	PiPScaleQuarter    PiPScale = 3
+	PiPScaleOneAndHalf PiPScale = 4
+	PiPScaleFullScreen PiPScale = 5
+)
+
+var pipScaleNames = map[PiPScale]string{
+	PiPScaleNone:       "1x",
+	PiPScaleHalf:       "1/2x",
+	PiPScaleQuarter:    "1/4x",
+	PiPScaleOneAndHalf: "1.5x",
+	PiPScaleFullScreen: "Full screen",
+}
+
+func (ps PiPScale) String() string {
+	if name, ok := pipScaleNames[ps]; ok {
+		return name
+	}
+	return fmt.Sprintf("PiPScale(%d)", byte(ps))
+}
+
+// Factor returns the factor the secondary video is scaled by, 0 for full screen and unknown scales
+func (ps PiPScale) Factor() float64 {
+	switch ps {
+	case PiPScaleNone:
+		return 1
+	case PiPScaleHalf:
+		return 0.5
+	case PiPScaleQuarter:
+		return 0.25
+	case PiPScaleOneAndHalf:
+		return 1.5
+	}
+	return 0
+}
+
+func (pe PiPEntry) String() string {
+	return fmt.Sprintf("%s %d,%d %s", pe.Time, pe.X, pe.Y, pe.Scale)
+}
+
+// At returns the entry in effect at t, the last entry starting at or before t.
+// It is false if t is before the first entry
+func (pm PiPMetadata) At(t Ticks) (PiPEntry, bool) {
+	var (
+		entry PiPEntry
+		found bool
+	)
+	for _, e := range pm.Entries {
+		if e.Time > t {
+			break
+		}
+		entry, found = e, true
+	}
+	return entry, found
+}
+
+// PiP returns the PiP metadata of secondary video stream stream of the PlayItem at index playitem.
+// It is false if the playlist has no metadata for the stream
+func (mpls *MPLS) PiP(playitem, stream int) (PiPMetadata, bool) {
+	for _, metadata := range mpls.ExtensionData.PiPMetadata {
+		if int(metadata.PlayItemRef) == playitem && int(metadata.SecondaryVideoRef) == stream {
+			return metadata, true
+		}
+	}
+	return PiPMetadata{}, false
+}
+
+// PiPStream returns the secondary video stream pm describes.
+// It is false if pm refers to a PlayItem or stream the playlist does not have
+func (mpls *MPLS) PiPStream(pm PiPMetadata) (SecondaryVideoStream, bool) {
+	if int(pm.PlayItemRef) >= len(mpls.Playlist.PlayItems) {
+		return SecondaryVideoStream{}, false
+	}
+	streams := mpls.Playlist.PlayItems[pm.PlayItemRef].StreamTable.SecondaryVideoStreams
+	if int(pm.SecondaryVideoRef) >= len(streams) {
+		return SecondaryVideoStream{}, false
+	}
+	return streams[pm.SecondaryVideoRef], true
+}
@@ -0,0 +1,121 @@
+package mpls
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// pipPlaylist returns editPlaylist with two secondary video streams on every PlayItem
+// and PiP metadata for the second stream of every PlayItem
+func pipPlaylist() *MPLS {
+	mpls := editPlaylist()
+	for i := range mpls.Playlist.PlayItems {
+		stnt := &mpls.Playlist.PlayItems[i].StreamTable
+		for _, pid := range []uint16{0x1B00, 0x1B01} {
+			stnt.SecondaryVideoStreams = append(stnt.SecondaryVideoStreams, SecondaryVideoStream{
+				PrimaryStream: PrimaryStream{
+					StreamEntry:      StreamEntry{Type: 1, PID: pid},
+					StreamAttributes: StreamAttributes{Encoding: VTH264, Format: 1, Rate: 3},
+				},
+			})
+		}
+		mpls.ExtensionData.PiPMetadata = append(mpls.ExtensionData.PiPMetadata, PiPMetadata{
+			PlayItemRef:       uint16(i),
+			SecondaryVideoRef: 1,
+			TimelineType:      PiPTimelineSynchronous,
+			LumaKey:           true,
+			UpperLimitLumaKey: 16,
+			Entries: []PiPEntry{
+				{Time: 1000, X: 100, Y: 200, Scale: PiPScaleHalf},
+				{Time: 3000, X: 1820, Y: 980, Scale: PiPScaleQuarter},
+			},
+		})
+	}
+	mpls.ExtensionData.Entries = []ExtensionEntry{{ID1: 1, ID2: 1}}
+	mpls.update()
+	return mpls
+}
+
+func TestPiPMetadataRoundTrip(t *testing.T) {
+	mpls := pipPlaylist()
+	mpls.ExtensionData.PiPMetadata[1].padding = []byte{0xEE, 0xEE}
+	mpls.ExtensionData.PiPMetadata[1].reserved = [4]byte{1, 2, 3, 4}
+	mpls.ExtensionData.PiPMetadata[1].flags = 0x0155
+	mpls.ExtensionData.PiPMetadata[1].Entries[0].reserved = 0x0A
+
+	file, err := mpls.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed MPLS
+	if err = parsed.Parse(file); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Warnings) != 0 {
+		t.Errorf("warnings: %v", parsed.Warnings)
+	}
+
+	for i := range parsed.ExtensionData.PiPMetadata {
+		mpls.ExtensionData.PiPMetadata[i].DataAddress = parsed.ExtensionData.PiPMetadata[i].DataAddress
+	}
+	if !reflect.DeepEqual(parsed.ExtensionData.PiPMetadata, mpls.ExtensionData.PiPMetadata) {
+		t.Errorf("PiPMetadata = %+v, want %+v", parsed.ExtensionData.PiPMetadata, mpls.ExtensionData.PiPMetadata)
+	}
+	// the entries follow the three 14 byte headers after the length and count
+	if got, want := parsed.ExtensionData.PiPMetadata[0].DataAddress, 6+3*14; got != want {
+		t.Errorf("first data address = %d, want %d", got, want)
+	}
+
+	again, err := parsed.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, again) {
+		t.Error("re-encoding the parsed playlist changed it")
+	}
+}
+
+func TestPiPMetadataEncodeInvalid(t *testing.T) {
+	mpls := pipPlaylist()
+	mpls.ExtensionData.PiPMetadata[0].Entries[0].X = 0x1000
+	if _, err := mpls.MarshalBinary(); err == nil {
+		t.Error("an X that does not fit in 12 bits was encoded")
+	}
+}
+
+func TestPiPMetadataEdits(t *testing.T) {
+	mpls := pipPlaylist()
+	if err := mpls.ReorderPlayItems([]int{2, 0}); err != nil {
+		t.Fatal(err)
+	}
+	var refs []uint16
+	for _, pm := range mpls.ExtensionData.PiPMetadata {
+		refs = append(refs, pm.PlayItemRef)
+	}
+	if want := []uint16{1, 0}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("PlayItemRefs after reordering = %v, want %v", refs, want)
+	}
+
+	// dropping the first secondary video stream renumbers the metadata of the second
+	mpls.DropStreams(func(kind StreamKind, stream PrimaryStream) bool {
+		return kind != StreamSecondaryVideo || stream.PID != 0x1B00
+	})
+	if len(mpls.ExtensionData.PiPMetadata) != 2 {
+		t.Fatalf("%d PiP metadata blocks, want 2", len(mpls.ExtensionData.PiPMetadata))
+	}
+	for _, pm := range mpls.ExtensionData.PiPMetadata {
+		stream, ok := mpls.PiPStream(pm)
+		if pm.SecondaryVideoRef != 0 || !ok || stream.PID != 0x1B01 {
+			t.Errorf("metadata %+v refers to stream %+v, want the remaining stream 0x1B01", pm, stream)
+		}
+	}
+
+	// dropping the stream the metadata describes removes the metadata
+	mpls.DropStreams(func(kind StreamKind, stream PrimaryStream) bool {
+		return kind != StreamSecondaryVideo
+	})
+	if len(mpls.ExtensionData.PiPMetadata) != 0 {
+		t.Errorf("PiP metadata of removed streams was kept: %+v", mpls.ExtensionData.PiPMetadata)
+	}
+}
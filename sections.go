@@ -0,0 +1,80 @@
+package mpls
+
+import (
+	"io"
+
+	"timmy.narnian.us/mpls/internal/bdparse"
+)
+
+// Section selects the sections of a playlist ParseSections reads
+type Section int
+
+// Sections of a playlist, the header is always read
+const (
+	SectionAppInfo Section = 1 << iota
+	SectionPlaylist
+	SectionMarks
+	SectionExtensionData
+
+	SectionAll = SectionAppInfo | SectionPlaylist | SectionMarks | SectionExtensionData
+)
+
+// headerLen is the length of the type indicator, version, section start addresses and reserved bytes
+const headerLen = 40
+
+// ParseSections parses the header and the sections selected by sections of the size byte playlist in r,
+// reading nothing but those sections. r is read in blocks as the sections are parsed, it is never read whole.
+// SegmentMap and Duration need SectionPlaylist, Chapters need SectionPlaylist and SectionMarks.
+// Sections left out are empty and the padding between sections is not kept,
+// only a playlist parsed with SectionAll can be written back with MarshalBinary
+func ParseSections(r io.ReaderAt, size int64, sections Section) (mpls MPLS, err error) {
+	err = mpls.parse(bdparse.NewSource(r, 0, size), sections)
+	return mpls, err
+}
+
+// ParseReadSeeker is ParseSections for the playlist in rs from its current position to its end.
+// rs is read with ReadAt if it is also an io.ReaderAt
+func ParseReadSeeker(rs io.ReadSeeker, sections Section) (MPLS, error) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return MPLS{}, err
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return MPLS{}, err
+	}
+
+	var r io.ReaderAt = readSeekerAt{rs}
+	if ra, ok := rs.(io.ReaderAt); ok {
+		r = ra
+	}
+	return ParseSections(io.NewSectionReader(r, start, end-start), end-start, sections)
+}
+
+// ParseHeader parses the header and AppInfoPlaylist of the playlist in rs
+func ParseHeader(rs io.ReadSeeker) (MPLS, error) {
+	return ParseReadSeeker(rs, SectionAppInfo)
+}
+
+// ParseMarks parses the header and PlaylistMark of the playlist in rs.
+// Chapters are not filled in as they need the PlayItems, see ParseSections
+func ParseMarks(rs io.ReadSeeker) (MPLS, error) {
+	return ParseReadSeeker(rs, SectionMarks)
+}
+
+// readSeekerAt reads at an offset by seeking, it is not safe for concurrent use
+type readSeekerAt struct {
+	rs io.ReadSeeker
+}
+
+func (r readSeekerAt) ReadAt(p []byte, off int64) (int, error) {
+	_, err := r.rs.Seek(off, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,136 @@
+package mpls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// onlyReaderAt hides every method of a *bytes.Reader but ReadAt so it is read like a file
+type onlyReaderAt struct {
+	r io.ReaderAt
+}
+
+func (r onlyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.r.ReadAt(p, off)
+}
+
+// sectionsFile returns a playlist larger than the read buffer, with padding between its sections
+func sectionsFile(t *testing.T) []byte {
+	mpls := pipPlaylist()
+	for i := 0; i < 1000; i++ {
+		mpls.MarkPlaylist.Marks = append(mpls.MarkPlaylist.Marks, Mark{Type: MarkEntry, PlayItemRef: 2, Time: Ticks(1000 + i), PID: 0xFFFF})
+	}
+	mpls.padding = [4][]byte{{1}, {2, 2}, {3, 3, 3}, {4}}
+	mpls.update()
+
+	file, err := mpls.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestParseSectionsMatchesParse(t *testing.T) {
+	file := sectionsFile(t)
+	want, err := Parse(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file) < 8192 {
+		t.Fatalf("test playlist is %d bytes, it should span several read buffers", len(file))
+	}
+
+	r, size := onlyReaderAt{bytes.NewReader(file)}, int64(len(file))
+	all, err := ParseSections(r, size, SectionAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Error("ParseSections with SectionAll differs from Parse")
+	}
+
+	var fromBytes MPLS
+	if err = fromBytes.Parse(file); err != nil || !reflect.DeepEqual(fromBytes, want) {
+		t.Errorf("MPLS.Parse differs from Parse, error %v", err)
+	}
+
+	// a seekable reader is parsed from its current position, other readers are read whole
+	prefixed := bytes.NewReader(append([]byte("junk"), file...))
+	_, _ = prefixed.Seek(4, io.SeekStart)
+	if got, err := Parse(prefixed); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse of a seekable reader differs, error %v", err)
+	}
+	if got, err := Parse(ioutil.NopCloser(bytes.NewReader(file))); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse of a reader that can not seek differs, error %v", err)
+	}
+
+	for _, test := range []struct {
+		sections Section
+		got      func(mpls MPLS) interface{}
+	}{
+		{SectionAppInfo, func(mpls MPLS) interface{} { return mpls.AppInfoPlaylist }},
+		{SectionPlaylist, func(mpls MPLS) interface{} { return mpls.Playlist }},
+		{SectionMarks, func(mpls MPLS) interface{} { return mpls.MarkPlaylist }},
+		{SectionPlaylist | SectionMarks, func(mpls MPLS) interface{} { return mpls.Chapters }},
+		{SectionPlaylist | SectionExtensionData, func(mpls MPLS) interface{} { return mpls.ExtensionData }},
+	} {
+		got, err := ParseSections(r, size, test.sections)
+		if err != nil {
+			t.Errorf("sections %b: %v", test.sections, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.got(got), test.got(want)) {
+			t.Errorf("sections %b differ from Parse", test.sections)
+		}
+	}
+}
+
+func TestParseSectionsErrors(t *testing.T) {
+	file := sectionsFile(t)
+	sections := []struct {
+		section Section
+		name    string
+	}{
+		{SectionAppInfo, "AppInfoPlaylist"},
+		{SectionPlaylist, "Playlist"},
+		{SectionMarks, "MarkPlaylist"},
+		{SectionExtensionData, "ExtensionData"},
+	}
+
+	for cut := 0; cut < len(file); cut += 7 {
+		_, want := Parse(bytes.NewReader(file[:cut]))
+		r, size := onlyReaderAt{bytes.NewReader(file[:cut])}, int64(cut)
+
+		_, err := ParseSections(r, size, SectionAll)
+		if !sameError(err, want) {
+			t.Errorf("cut at %d: ParseSections error %v, Parse error %v", cut, err, want)
+		}
+
+		var parseErr *ParseError
+		if !errors.As(want, &parseErr) {
+			continue
+		}
+		for _, section := range sections {
+			if parseErr.Section != "" && !strings.HasPrefix(parseErr.Section, section.name) {
+				continue
+			}
+			_, err = ParseSections(r, size, section.section)
+			if !sameError(err, want) {
+				t.Errorf("cut at %d: %s error %v, Parse error %v", cut, section.name, err, want)
+			}
+		}
+	}
+}
+
+// sameError reports whether both errors are nil or have the same message, which includes the offset
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
@@ -0,0 +1,37 @@
+package mpls
+
+// MVCDependentViewPID is the PID MVC dependent views are muxed with
+const MVCDependentViewPID = 0x1012
+
+// DependentView is the MVC dependent view of a main path PlayItem
+type DependentView struct {
+	VideoLayer
+	SSIF string // the interleaved base and dependent view file, BDMV/STREAM/SSIF/<SSIF>.ssif
+}
+
+// IsStereoscopic reports whether the playlist is a 3D playlist with an MVC dependent view
+func (mpls *MPLS) IsStereoscopic() bool {
+	return mpls.hasVideoLayer(dependentViewEntries, SPTStereoscopicVideo)
+}
+
+// DependentViews returns the dependent view of every main path PlayItem that has one.
+// The views are found through the STN_table_SS of each PlayItem, whose entries refer to the extension data SubPaths.
+// PlayItems without one fall back to the stereoscopic SubPaths with MVCDependentViewPID
+func (mpls *MPLS) DependentViews() []DependentView {
+	var views []DependentView
+	for _, layer := range mpls.videoLayers(dependentViewEntries, mpls.ExtensionData.SubPaths, SPTStereoscopicVideo, MVCDependentViewPID) {
+		views = append(views, DependentView{
+			VideoLayer: layer,
+			SSIF:       mpls.Playlist.PlayItems[layer.PlayItem].Clpi.ClipFile,
+		})
+	}
+	return views
+}
+
+func dependentViewEntries(stnt STNTable) []StreamEntry {
+	entries := make([]StreamEntry, 0, len(stnt.SS.DependentViewStreams))
+	for _, stream := range stnt.SS.DependentViewStreams {
+		entries = append(entries, stream.StreamEntry)
+	}
+	return entries
+}
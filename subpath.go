@@ -0,0 +1,193 @@
+package mpls
+
+import "fmt"
+
+// SubPathType is the SubPath_type of a SubPath
+type SubPathType byte
+
+// SubPath types
+const (
+	SPTPrimaryAudioSlideshow   SubPathType = 2  // primary audio of a browsable slideshow
+	SPTInteractiveGraphics     SubPathType = 3  // interactive graphics presentation menu
+	SPTTextSubtitle            SubPathType = 4  // text subtitle presentation
+	SPTOutOfMuxSynchronous     SubPathType = 5  // out of mux PG, IG, secondary audio or video synchronous with the main path
+	SPTOutOfMuxAsynchronousPiP SubPathType = 6  // out of mux picture in picture asynchronous with the main path
+	SPTInMuxSynchronousPiP     SubPathType = 7  // in mux picture in picture synchronous with the main path
+	SPTStereoscopicVideo       SubPathType = 8  // MVC dependent view
+	SPTDolbyVisionEL           SubPathType = 10 // Dolby Vision enhancement layer
+)
+
+var subPathTypeNames = map[SubPathType]string{
+	SPTPrimaryAudioSlideshow:   "Primary audio of browsable slideshow",
+	SPTInteractiveGraphics:     "Interactive graphics menu",
+	SPTTextSubtitle:            "Text subtitle",
+	SPTOutOfMuxSynchronous:     "Out of mux synchronous",
+	SPTOutOfMuxAsynchronousPiP: "Out of mux asynchronous picture in picture",
+	SPTInMuxSynchronousPiP:     "In mux synchronous picture in picture",
+	SPTStereoscopicVideo:       "MVC dependent view",
+	SPTDolbyVisionEL:           "Dolby Vision enhancement layer",
+}
+
+func (spt SubPathType) String() string {
+	if name, ok := subPathTypeNames[spt]; ok {
+		return name
+	}
+	return fmt.Sprintf("SubPathType(%d)", byte(spt))
+}
+
+// IsSynchronous reports whether SubPlayItems of this type are placed on the main path timeline
+// by their PlayItemID and StartOfPlayitem
+func (spt SubPathType) IsSynchronous() bool {
+	switch spt {
+	case SPTTextSubtitle, SPTOutOfMuxSynchronous, SPTInMuxSynchronousPiP, SPTStereoscopicVideo, SPTDolbyVisionEL:
+		return true
+	}
+	return false
+}
+
+// IsRepeat reports whether the SubPath repeats while the main path plays
+func (sp SubPath) IsRepeat() bool {
+	return sp.Flags&SPFIsRepeat != 0
+}
+
+// SubPlayItemSpan is a SubPlayItem placed on the playlist timeline.
+// Start and End are relative to the start of the playlist
+type SubPlayItemSpan struct {
+	SubPlayItem SubPlayItem
+	Start       Ticks
+	End         Ticks
+}
+
+// SubPlayItemStart returns where spi starts on the playlist timeline, relative to the start of the playlist.
+// It is false if spi refers to a PlayItem the playlist does not have
+func (mpls *MPLS) SubPlayItemStart(spi SubPlayItem) (Ticks, bool) {
+	if int(spi.PlayItemID) >= len(mpls.Playlist.PlayItems) {
+		return 0, false
+	}
+	start := mpls.playItemOffsets()[spi.PlayItemID]
+	if playitem := mpls.Playlist.PlayItems[spi.PlayItemID]; spi.StartOfPlayitem > playitem.InTime {
+		start += spi.StartOfPlayitem - playitem.InTime
+	}
+	return start, true
+}
+
+// Timeline places the SubPlayItems of a synchronous SubPath on the playlist timeline.
+// SubPlayItems of asynchronous SubPaths and those referring to a PlayItem the playlist does not have are left out
+func (mpls *MPLS) Timeline(sp SubPath) []SubPlayItemSpan {
+	var spans []SubPlayItemSpan
+	if !sp.Type.IsSynchronous() {
+		return nil
+	}
+	for _, spi := range sp.SubPlayItems {
+		start, ok := mpls.SubPlayItemStart(spi)
+		if !ok {
+			continue
+		}
+		spans = append(spans, SubPlayItemSpan{
+			SubPlayItem: spi,
+			Start:       start,
+			End:         start + spi.Length(),
+		})
+	}
+	return spans
+}
+
+// playItemOffsets returns where each PlayItem starts on the playlist timeline
+// followed by the length of the playlist
+func (mpls *MPLS) playItemOffsets() []Ticks {
+	offsets := make([]Ticks, len(mpls.Playlist.PlayItems)+1)
+	for i, playitem := range mpls.Playlist.PlayItems {
+		offsets[i+1] = offsets[i] + playitem.Length()
+	}
+	return offsets
+}
+
+// subPathClips returns the clips and PIDs of the streams of PlayItem playitem given by entries.
+// Entries carried by a SubPath refer to the SubPath of type typ at their SubPathID in subpaths,
+// when there are no entries the SubPaths of type typ of the playlist and its extension data are used with pid
+func (mpls *MPLS) subPathClips(playitem int, entries []StreamEntry, subpaths []SubPath, typ SubPathType, pid uint16) ([]string, []uint16) {
+	var (
+		clips []string
+		pids  []uint16
+	)
+	add := func(clip string, pid uint16) {
+		if !containsString(clips, clip) {
+			clips = append(clips, clip)
+		}
+		if !containsPID(pids, pid) {
+			pids = append(pids, pid)
+		}
+	}
+
+	for _, entry := range entries {
+		if !entry.fromSubPath() {
+			add(mpls.Playlist.PlayItems[playitem].Clpi.ClipFile, entry.PID)
+			continue
+		}
+		if int(entry.SubPathID) >= len(subpaths) || subpaths[entry.SubPathID].Type != typ {
+			continue
+		}
+		if spi, ok := subpaths[entry.SubPathID].subPlayItem(playitem); ok {
+			add(spi.clip(entry.SubClipID).ClipFile, entry.PID)
+		}
+	}
+
+	if len(entries) == 0 {
+		for _, subpath := range mpls.subPathsOfType(typ) {
+			if spi, ok := subpath.subPlayItem(playitem); ok {
+				add(spi.Clpi.ClipFile, pid)
+			}
+		}
+	}
+	return clips, pids
+}
+
+// subPathsOfType returns the SubPaths of type typ of the playlist and its extension data
+func (mpls *MPLS) subPathsOfType(typ SubPathType) []SubPath {
+	var found []SubPath
+	for _, subpaths := range [][]SubPath{mpls.Playlist.SubPaths, mpls.ExtensionData.SubPaths} {
+		for _, subpath := range subpaths {
+			if subpath.Type == typ {
+				found = append(found, subpath)
+			}
+		}
+	}
+	return found
+}
+
+// subPlayItem returns the SubPlayItem played with PlayItem playitem.
+// SubPlayItems of synchronous SubPaths are matched by PlayItemID
+func (sp SubPath) subPlayItem(playitem int) (SubPlayItem, bool) {
+	for _, spi := range sp.SubPlayItems {
+		if int(spi.PlayItemID) == playitem {
+			return spi, true
+		}
+	}
+	return SubPlayItem{}, false
+}
+
+// clip returns the clip of a multi clip SubPlayItem selected by a stream entry SubClipID
+func (spi SubPlayItem) clip(id byte) CLPI {
+	if id == 0 || int(id) > len(spi.Angles) {
+		return spi.Clpi
+	}
+	return spi.Angles[id-1]
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPID(list []uint16, pid uint16) bool {
+	for _, v := range list {
+		if v == pid {
+			return true
+		}
+	}
+	return false
+}
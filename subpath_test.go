@@ -0,0 +1,70 @@
+package mpls
+
+import (
+	"reflect"
+	"testing"
+)
+
+// timelinePlaylist returns a playlist of PlayItems of 4000, 6000 and 1000 ticks that do not start at 0
+func timelinePlaylist() *MPLS {
+	mpls := &MPLS{}
+	for _, times := range [][2]Ticks{{1000, 5000}, {2000, 8000}, {500, 1500}} {
+		mpls.Playlist.PlayItems = append(mpls.Playlist.PlayItems, PlayItem{InTime: times[0], OutTime: times[1]})
+	}
+	return mpls
+}
+
+func TestPlayItemOffsets(t *testing.T) {
+	if offsets := timelinePlaylist().playItemOffsets(); !reflect.DeepEqual(offsets, []Ticks{0, 4000, 10000, 11000}) {
+		t.Errorf("offsets = %v, want [0 4000 10000 11000]", offsets)
+	}
+	if offsets := (&MPLS{}).playItemOffsets(); !reflect.DeepEqual(offsets, []Ticks{0}) {
+		t.Errorf("offsets of an empty playlist = %v, want [0]", offsets)
+	}
+}
+
+func TestSubPlayItemStart(t *testing.T) {
+	mpls := timelinePlaylist()
+	tests := []struct {
+		spi   SubPlayItem
+		start Ticks
+		ok    bool
+	}{
+		{SubPlayItem{PlayItemID: 0, StartOfPlayitem: 1000}, 0, true},
+		// 1500 into PlayItem 1, which starts 4000 into the playlist
+		{SubPlayItem{PlayItemID: 1, StartOfPlayitem: 3500}, 5500, true},
+		// a start before the InTime of the PlayItem is the start of the PlayItem
+		{SubPlayItem{PlayItemID: 2, StartOfPlayitem: 200}, 10000, true},
+		{SubPlayItem{PlayItemID: 3, StartOfPlayitem: 1000}, 0, false},
+	}
+	for _, test := range tests {
+		if start, ok := mpls.SubPlayItemStart(test.spi); start != test.start || ok != test.ok {
+			t.Errorf("PlayItemID %d StartOfPlayitem %d starts at %d %v, want %d %v",
+				test.spi.PlayItemID, test.spi.StartOfPlayitem, start, ok, test.start, test.ok)
+		}
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	mpls := timelinePlaylist()
+	sp := SubPath{
+		Type: SPTOutOfMuxSynchronous,
+		SubPlayItems: []SubPlayItem{
+			{PlayItemID: 1, StartOfPlayitem: 3500, InTime: 100, OutTime: 2100},
+			{PlayItemID: 2, StartOfPlayitem: 600, InTime: 0, OutTime: 900},
+			{PlayItemID: 3, StartOfPlayitem: 0, InTime: 0, OutTime: 900},
+		},
+	}
+	want := []SubPlayItemSpan{
+		{SubPlayItem: sp.SubPlayItems[0], Start: 5500, End: 7500},
+		{SubPlayItem: sp.SubPlayItems[1], Start: 10100, End: 11000},
+	}
+	if spans := mpls.Timeline(sp); !reflect.DeepEqual(spans, want) {
+		t.Errorf("timeline = %+v, want %+v", spans, want)
+	}
+
+	sp.Type = SPTPrimaryAudioSlideshow
+	if spans := mpls.Timeline(sp); spans != nil {
+		t.Errorf("timeline of an asynchronous sub path = %+v, want none", spans)
+	}
+}
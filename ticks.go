@@ -0,0 +1,74 @@
+package mpls
+
+import (
+	"fmt"
+	"time"
+)
+
+// TicksPerSecond is the rate of the 45 kHz clock used for the times in a playlist
+const TicksPerSecond = 45000
+
+// Ticks is a time or duration on the 45 kHz playlist clock
+type Ticks uint32
+
+// Duration returns t as a time.Duration rounded to the nearest nanosecond
+func (t Ticks) Duration() time.Duration {
+	return ticksDuration(int64(t))
+}
+
+// String formats t as h:mm:ss.mmm
+func (t Ticks) String() string {
+	ms := (uint64(t)*1000 + TicksPerSecond/2) / TicksPerSecond
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// PTS returns t on the 90 kHz clock of the MPEG-TS timestamps, used by the EP map of a clip
+func (t Ticks) PTS() uint64 {
+	return uint64(t) * 2
+}
+
+// TicksFromPTS converts a 90 kHz MPEG-TS timestamp to the 45 kHz playlist clock, rounding down
+func TicksFromPTS(pts uint64) Ticks {
+	return Ticks(pts / 2)
+}
+
+// ticksDuration converts a count of 45 kHz ticks to a time.Duration rounded to the nearest nanosecond
+func ticksDuration(ticks int64) time.Duration {
+	return time.Duration((ticks*int64(time.Second) + TicksPerSecond/2) / TicksPerSecond)
+}
+
+// Length returns the length of the PlayItem, 0 if OutTime is before InTime
+func (pi PlayItem) Length() Ticks {
+	if pi.OutTime < pi.InTime {
+		return 0
+	}
+	return pi.OutTime - pi.InTime
+}
+
+// Duration returns the length of the PlayItem as a time.Duration
+func (pi PlayItem) Duration() time.Duration {
+	return pi.Length().Duration()
+}
+
+// Length returns the length of the SubPlayItem, 0 if OutTime is before InTime
+func (spi SubPlayItem) Length() Ticks {
+	if spi.OutTime < spi.InTime {
+		return 0
+	}
+	return spi.OutTime - spi.InTime
+}
+
+// Duration returns the length of the SubPlayItem as a time.Duration
+func (spi SubPlayItem) Duration() time.Duration {
+	return spi.Length().Duration()
+}
+
+// Length returns the length of the playlist, the sum of the lengths of its PlayItems.
+// It is an int64 as long playlists can overflow Ticks
+func (mpls *MPLS) Length() int64 {
+	var ticks int64
+	for _, playitem := range mpls.Playlist.PlayItems {
+		ticks += int64(playitem.Length())
+	}
+	return ticks
+}
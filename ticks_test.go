@@ -0,0 +1,83 @@
+package mpls
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicksDuration(t *testing.T) {
+	tests := []struct {
+		ticks Ticks
+		want  time.Duration
+	}{
+		{0, 0},
+		{1, 22222 * time.Nanosecond},       // 22222.2ns
+		{2, 44444 * time.Nanosecond},       // 44444.4ns
+		{7, 155556 * time.Nanosecond},      // 155555.6ns rounds up
+		{45, time.Millisecond},             // exact, no float rounding
+		{1876, 41688889 * time.Nanosecond}, // a 23.976 fps frame truncated to whole ticks
+		{TicksPerSecond, time.Second},
+		{3661*TicksPerSecond + 1, time.Hour + time.Minute + time.Second + 22222*time.Nanosecond},
+		// the longest Ticks, the nanoseconds are computed in integers without losing a digit
+		{^Ticks(0), 95443717666667 * time.Nanosecond},
+	}
+	for _, test := range tests {
+		if d := test.ticks.Duration(); d != test.want {
+			t.Errorf("%d ticks = %v, want %v", test.ticks, d, test.want)
+		}
+	}
+}
+
+func TestTicksString(t *testing.T) {
+	tests := []struct {
+		ticks Ticks
+		want  string
+	}{
+		{0, "0:00:00.000"},
+		{22, "0:00:00.000"},
+		{23, "0:00:00.001"},
+		{45 * 999, "0:00:00.999"},
+		{3661*TicksPerSecond + 45*7, "1:01:01.007"},
+		{^Ticks(0), "26:30:43.718"},
+	}
+	for _, test := range tests {
+		if s := test.ticks.String(); s != test.want {
+			t.Errorf("%d ticks = %q, want %q", test.ticks, s, test.want)
+		}
+	}
+}
+
+func TestTicksPTS(t *testing.T) {
+	if pts := Ticks(45000).PTS(); pts != 90000 {
+		t.Errorf("PTS of 1s = %d, want 90000", pts)
+	}
+	if ticks := TicksFromPTS(90001); ticks != 45000 {
+		t.Errorf("90001 at 90 kHz = %d ticks, want 45000", ticks)
+	}
+	// PTS values past the 32 bit range of Ticks come from 33 bit MPEG-TS timestamps
+	if ticks := TicksFromPTS(1<<33 - 2); ticks != 1<<32-1 {
+		t.Errorf("largest PTS = %d ticks, want %d", ticks, Ticks(1<<32-1))
+	}
+	for _, ticks := range []Ticks{0, 1, 1000, ^Ticks(0)} {
+		if back := TicksFromPTS(ticks.PTS()); back != ticks {
+			t.Errorf("%d ticks became %d going through PTS", ticks, back)
+		}
+	}
+}
+
+func TestMPLSDuration(t *testing.T) {
+	mpls := editPlaylist()
+	mpls.Playlist.PlayItems[2].OutTime += 1
+	mpls.update()
+
+	// three PlayItems of 4000 ticks and one tick
+	if mpls.Length() != 12001 {
+		t.Errorf("Length = %d, want 12001", mpls.Length())
+	}
+	if want := 266688889 * time.Nanosecond; mpls.Duration != want {
+		t.Errorf("Duration = %v, want %v", mpls.Duration, want)
+	}
+	if d := mpls.Playlist.PlayItems[2].Duration(); d != 88911111*time.Nanosecond {
+		t.Errorf("PlayItem Duration = %v, want 88.911111ms", d)
+	}
+}
@@ -0,0 +1,51 @@
+package mpls
+
+import "fmt"
+
+// WarningKind identifies the kind of problem a Warning reports
+type WarningKind int
+
+// Warning kinds
+const (
+	WarnMisaligned      WarningKind = iota // a section did not end where its length says it should
+	WarnSectionStart                       // a section did not start at the address in the header
+	WarnVersion                            // the file version is not one that is known to work
+	WarnUnknownCodecID                     // a clip has a codec identifier other than M2TS
+	WarnUnknownEncoding                    // a stream has an unrecognized coding type
+)
+
+func (wk WarningKind) String() string {
+	switch wk {
+	case WarnMisaligned:
+		return "not aligned"
+	case WarnSectionStart:
+		return "wrong start"
+	case WarnVersion:
+		return "unknown version"
+	case WarnUnknownCodecID:
+		return "unknown codec id"
+	case WarnUnknownEncoding:
+		return "unrecognized encoding"
+	}
+	return fmt.Sprintf("WarningKind(%d)", int(wk))
+}
+
+// Warning is a non-fatal problem found while parsing.
+// Section is the path of the structure being parsed e.g. Playlist.PlayItems[2].StreamTable.
+// Expected and Actual are byte offsets in the file. Actual is where parsing was when the warning was found.
+// For WarnMisaligned and WarnSectionStart Expected is where parsing should have been,
+// for the other kinds it is the offset of the field the warning is about
+type Warning struct {
+	Kind     WarningKind
+	Section  string
+	Expected int64
+	Actual   int64
+	Message  string
+}
+
+func (w Warning) String() string {
+	if w.Message != "" {
+		return fmt.Sprintf("%s: %s: %s", w.Section, w.Kind, w.Message)
+	}
+	return fmt.Sprintf("%s: %s: current position is %d position should be %d", w.Section, w.Kind, w.Actual, w.Expected)
+}